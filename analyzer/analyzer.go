@@ -0,0 +1,102 @@
+// Package analyzer provides a go/analysis Analyzer reporting trailing
+// whitespace and final newline problems in Go source files, for use with
+// go vet -vettool, golangci-lint and other analysis drivers.
+package analyzer
+
+import (
+	"bytes"
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/scottrigby/whitespace-tools/internal/whitespace"
+)
+
+const doc = `report trailing whitespace and missing or extra final newlines
+
+The whitespace analyzer reports what the trailingspace and newline tools
+would fix in each Go source file, with a suggested fix per problem.
+Whitespace inside raw string literals is left alone, and generated files
+are skipped.`
+
+// Analyzer reports trailing whitespace and final newline problems
+var Analyzer = &analysis.Analyzer{
+	Name: "whitespace",
+	Doc:  doc,
+	URL:  "https://github.com/scottrigby/whitespace-tools",
+	Run:  run,
+}
+
+// rules is the -rules flag, a comma separated list like the tools' --rules
+var rules string
+
+func init() {
+	Analyzer.Flags.StringVar(&rules, "rules", "", "comma separated rules to apply: trailingspace, newline (default: all)")
+}
+
+var utf8BOM = []byte("\uFEFF")
+
+var fixMessages = map[string]string{
+	whitespace.RuleTrailingspace: "Remove trailing whitespace",
+	whitespace.RuleNewline:       "End file with a single newline",
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	selected, err := whitespace.ParseRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range pass.Files {
+		if ast.IsGenerated(file) {
+			continue
+		}
+		tf := pass.Fset.File(file.Pos())
+		// cgo and other preprocessed files may not map to Go source
+		if tf == nil || !strings.HasSuffix(tf.Name(), ".go") {
+			continue
+		}
+		content, err := pass.ReadFile(tf.Name())
+		if err != nil {
+			return nil, err
+		}
+		if len(content) != tf.Size() {
+			// The file changed since it was parsed
+			continue
+		}
+		problems, err := whitespace.Problems(tf.Name(), content, selected, whitespace.Options{})
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range problems {
+			report(pass, tf, content, p)
+		}
+	}
+	return nil, nil
+}
+
+// report reports p at the end of the text on its line, covering the text
+// its fix replaces
+func report(pass *analysis.Pass, tf *token.File, content []byte, p whitespace.Problem) {
+	edits := make([]analysis.TextEdit, len(p.Edits))
+	for i, e := range p.Edits {
+		edits[i] = analysis.TextEdit{Pos: tf.Pos(e.Start), End: tf.Pos(e.End), NewText: []byte(e.Text)}
+	}
+	start := tf.LineStart(p.Line)
+	if p.Line == 1 && bytes.HasPrefix(content, utf8BOM) {
+		// The text of a finding leaves out the byte order mark
+		start += token.Pos(len(utf8BOM))
+	}
+	pos := start + token.Pos(len(strings.TrimRight(p.Text, " \t")))
+	pass.Report(analysis.Diagnostic{
+		Pos:      pos,
+		End:      max(pos, edits[len(edits)-1].End),
+		Category: p.Rule,
+		Message:  p.Message,
+		SuggestedFixes: []analysis.SuggestedFix{{
+			Message:   fixMessages[p.Rule],
+			TextEdits: edits,
+		}},
+	})
+}
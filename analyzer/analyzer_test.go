@@ -0,0 +1,28 @@
+package analyzer
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), Analyzer, "a")
+}
+
+func TestAnalyzer_BOM(t *testing.T) {
+	for _, r := range analysistest.Run(t, analysistest.TestData(), Analyzer, "a") {
+		for _, d := range r.Diagnostics {
+			pos := r.Pass.Fset.Position(d.Pos)
+			if filepath.Base(pos.Filename) != "c.go" {
+				continue
+			}
+			// Just past the BOM and the text of the line
+			want := len("\uFEFFpackage a // want \"trailing whitespace\"")
+			if pos.Offset != want {
+				t.Errorf("expected the diagnostic at offset %d, got %d", want, pos.Offset)
+			}
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package a
+
+import "fmt" // want "trailing whitespace"  
+
+// Raw strings keep their trailing whitespace
+var raw = `first line  
+second line	
+`
+
+func A() { // want "trailing whitespace" 
+	fmt.Println(raw) // want "trailing whitespace"	
+} // want "extra blank lines at end of file"
+
+
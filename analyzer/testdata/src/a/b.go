@@ -0,0 +1,3 @@
+package a
+
+var B = 1 // want "missing final newline"
\ No newline at end of file
@@ -0,0 +1,3 @@
+﻿package a // want "trailing whitespace"  
+
+var C = 1
@@ -2,8 +2,6 @@ package main
 
 import (
 	"flag"
-	"fmt"
-	"os"
 
 	"github.com/scottrigby/whitespace-tools/internal/cli"
 	"github.com/scottrigby/whitespace-tools/internal/whitespace"
@@ -29,15 +27,25 @@ func main() {
 		return
 	}
 
-	target, _ := cli.ParseTarget()
+	if cli.HandleListDefaultExcludes(flags.ListDefaultExcludes) {
+		return
+	}
+
+	targets := cli.ParseTargets()
+
+	opts, err := flags.Options()
+	cli.HandleError(err)
 
-	opts := whitespace.Options{
-		IncludeHidden:   flags.IncludeHidden,
-		ExcludePatterns: []string(flags.ExcludePatterns),
+	if flags.CheckMode() {
+		findings, err := cli.CheckTargets(targets, opts, whitespace.CheckNewlineWithOptions)
+		flags.FinishCheck(whitespace.RuleNewline, findings, err)
+		return
 	}
 
-	if err := whitespace.ProcessNewlineWithOptions(target, opts); err != nil {
-		fmt.Fprintln(os.Stderr, "Error:", err)
-		os.Exit(1)
+	if flags.Watch {
+		flags.Finish(opts, cli.WatchTargets(targets, opts, whitespace.WatchNewlineWithOptions))
+		return
 	}
+
+	flags.Finish(opts, cli.ProcessTargets(targets, opts, whitespace.ProcessNewlineWithOptions))
 }
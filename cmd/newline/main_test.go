@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain lets the test binary stand in for the newline command, so exit
+// codes and output can be tested
+func TestMain(m *testing.M) {
+	if os.Getenv("NEWLINE_TEST_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// writeFiles creates files under dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+		stderr []string // substrings expected on stderr
+	}{
+		{
+			name:   "clean files",
+			args:   []string{"--fail-on-change", "clean.txt", "dir/clean.txt"},
+			stderr: []string{"2 file(s) scanned, 0 fixed, 0 skipped"},
+		},
+		{
+			name:   "fail on change with many filenames",
+			args:   []string{"--fail-on-change", "a.txt", "clean.txt", "b.txt", "dir"},
+			code:   1,
+			stdout: "Fixed a.txt\nFixed b.txt\nFixed dir/c.txt\n",
+			stderr: []string{"3 file(s) modified"},
+		},
+		{
+			name:   "changes without fail on change",
+			args:   []string{"a.txt", "b.txt"},
+			stdout: "Fixed a.txt\nFixed b.txt\n",
+		},
+		{
+			name:   "keep going past a failing target",
+			args:   []string{"--keep-going", "--fail-on-change", "a.txt", "missing.txt", "b.txt"},
+			code:   1,
+			stdout: "Fixed a.txt\nFixed b.txt\n",
+			stderr: []string{"Error: lstat missing.txt", "2 file(s) scanned, 2 fixed"},
+		},
+		{
+			name:   "check",
+			args:   []string{"--check", "a.txt", "clean.txt", "b.txt"},
+			code:   1,
+			stdout: "a.txt:1:2: newline: missing final newline\n    a\nb.txt:1:2: newline: extra blank lines at end of file\n    b\n",
+			stderr: []string{"2 problem(s) found", "3 file(s) scanned, 2 with problems, 0 skipped"},
+		},
+		{
+			name: "check clean files",
+			args: []string{"--check", "clean.txt", "dir/clean.txt"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{
+				"a.txt":         "a",
+				"b.txt":         "b\n\n\n",
+				"clean.txt":     "clean\n",
+				"dir/c.txt":     "c",
+				"dir/clean.txt": "clean\n",
+			})
+			cmd := exec.Command(exe, tt.args...)
+			cmd.Dir = dir
+			cmd.Env = append(os.Environ(), "NEWLINE_TEST_MAIN=1", "NO_COLOR=1")
+			var stdout, stderr bytes.Buffer
+			cmd.Stdout, cmd.Stderr = &stdout, &stderr
+			err := cmd.Run()
+
+			code := 0
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				code = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if code != tt.code {
+				t.Errorf("expected exit code %d, got %d\nstderr: %s", tt.code, code, stderr.String())
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("expected stdout %q, got %q", tt.stdout, stdout.String())
+			}
+			for _, want := range tt.stderr {
+				if !strings.Contains(stderr.String(), want) {
+					t.Errorf("expected %q on stderr, got %q", want, stderr.String())
+				}
+			}
+		})
+	}
+}
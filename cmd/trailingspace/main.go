@@ -2,8 +2,6 @@ package main
 
 import (
 	"flag"
-	"fmt"
-	"os"
 
 	"github.com/scottrigby/whitespace-tools/internal/cli"
 	"github.com/scottrigby/whitespace-tools/internal/whitespace"
@@ -22,6 +20,7 @@ func main() {
 
 	cli.SetupUsage("Removes trailing whitespace from end of lines.")
 	flags.SetupFlags()
+	flags.SetupMarkdownFlags()
 	flag.Parse()
 
 	// Handle version flag
@@ -29,15 +28,25 @@ func main() {
 		return
 	}
 
-	target, _ := cli.ParseTarget()
+	if cli.HandleListDefaultExcludes(flags.ListDefaultExcludes) {
+		return
+	}
+
+	targets := cli.ParseTargets()
+
+	opts, err := flags.Options()
+	cli.HandleError(err)
 
-	opts := whitespace.Options{
-		IncludeHidden:   flags.IncludeHidden,
-		ExcludePatterns: []string(flags.ExcludePatterns),
+	if flags.CheckMode() {
+		findings, err := cli.CheckTargets(targets, opts, whitespace.CheckTrailingspaceWithOptions)
+		flags.FinishCheck(whitespace.RuleTrailingspace, findings, err)
+		return
 	}
 
-	if err := whitespace.ProcessTrailingspaceWithOptions(target, opts); err != nil {
-		fmt.Fprintln(os.Stderr, "Error:", err)
-		os.Exit(1)
+	if flags.Watch {
+		flags.Finish(opts, cli.WatchTargets(targets, opts, whitespace.WatchTrailingspaceWithOptions))
+		return
 	}
+
+	flags.Finish(opts, cli.ProcessTargets(targets, opts, whitespace.ProcessTrailingspaceWithOptions))
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/scottrigby/whitespace-tools/internal/cli"
+	"github.com/scottrigby/whitespace-tools/internal/whitespace"
+)
+
+// fixConfig holds the rule and file selection flags shared by the commands
+// that fix content handed to them by git
+type fixConfig struct {
+	rules             string
+	exclude           cli.ArrayFlags
+	include           cli.ArrayFlags
+	types             cli.ArrayFlags
+	noDefaultExcludes bool
+	includeGenerated  bool
+}
+
+func (c *fixConfig) setupFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.rules, "rules", "", "comma separated rules to apply: trailingspace, newline (default: all)")
+	fs.Var(&c.exclude, "exclude", "exclude files matching pattern (can be used multiple times)")
+	fs.Var(&c.include, "include", "only process files matching pattern (can be used multiple times)")
+	fs.Var(&c.types, "type", "only process files of these types, comma separated")
+	fs.BoolVar(&c.noDefaultExcludes, "no-default-excludes", false, "do not apply the built-in exclude patterns")
+	fs.BoolVar(&c.includeGenerated, "include-generated", false, "process generated files")
+}
+
+// args returns the command line flags that reproduce c
+func (c *fixConfig) args() []string {
+	var args []string
+	if c.rules != "" {
+		args = append(args, "--rules", c.rules)
+	}
+	for _, p := range c.exclude {
+		args = append(args, "--exclude", p)
+	}
+	for _, p := range c.include {
+		args = append(args, "--include", p)
+	}
+	for _, t := range c.types {
+		args = append(args, "--type", t)
+	}
+	if c.noDefaultExcludes {
+		args = append(args, "--no-default-excludes")
+	}
+	if c.includeGenerated {
+		args = append(args, "--include-generated")
+	}
+	return args
+}
+
+func (c *fixConfig) options() whitespace.Options {
+	var types []string
+	for _, t := range c.types {
+		types = append(types, strings.Split(t, ",")...)
+	}
+	return whitespace.Options{
+		ExcludePatterns:   c.exclude,
+		IncludePatterns:   c.include,
+		Types:             types,
+		NoDefaultExcludes: c.noDefaultExcludes,
+		IncludeGenerated:  c.includeGenerated,
+		Warn:              os.Stderr,
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/scottrigby/whitespace-tools/internal/gitfilter"
+	"github.com/scottrigby/whitespace-tools/internal/whitespace"
+)
+
+// runFilter implements: whitespace filter --clean [OPTIONS] [FILE]
+func runFilter(args []string) error {
+	var cfg fixConfig
+	fs := flag.NewFlagSet("filter", flag.ExitOnError)
+	clean := fs.Bool("clean", false, "fix content on its way into the index")
+	cfg.setupFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s filter --clean [OPTIONS] [FILE]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nA git filter driver that fixes whitespace whenever content is staged.\n")
+		fmt.Fprintf(os.Stderr, "Without FILE it speaks git's long-running filter process protocol:\n\n")
+		fmt.Fprintf(os.Stderr, "  git config filter.whitespace.process 'whitespace filter --clean'\n")
+		fmt.Fprintf(os.Stderr, "  echo '* filter=whitespace' >> .gitattributes\n\n")
+		fmt.Fprintf(os.Stderr, "With FILE it filters standard input once, for filter.<driver>.clean:\n\n")
+		fmt.Fprintf(os.Stderr, "  git config filter.whitespace.clean 'whitespace filter --clean %%f'\n\n")
+		fmt.Fprintf(os.Stderr, "OPTIONS:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if !*clean {
+		fs.Usage()
+		return errors.New("only --clean is supported")
+	}
+	if fs.NArg() > 1 {
+		return errors.New("too many arguments")
+	}
+	rules, err := whitespace.ParseRules(cfg.rules)
+	if err != nil {
+		return err
+	}
+	opts := cfg.options()
+
+	cleanFunc := func(pathname string, content []byte) ([]byte, error) {
+		ok, err := whitespace.SelectContent(pathname, content, opts)
+		if err != nil || !ok {
+			return content, err
+		}
+		return whitespace.FixContent(pathname, content, rules, opts)
+	}
+
+	if fs.NArg() == 0 {
+		return gitfilter.Serve(os.Stdin, os.Stdout, cleanFunc, os.Stderr)
+	}
+	content, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+	cleaned, err := cleanFunc(fs.Arg(0), content)
+	if err != nil {
+		// Pass the content through so that git add still works
+		fmt.Fprintf(os.Stderr, "%s: %v\n", fs.Arg(0), err)
+		cleaned = content
+	}
+	_, err = os.Stdout.Write(cleaned)
+	return err
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain lets the test binary stand in for the whitespace command, so git
+// can run it as a filter process
+func TestMain(m *testing.M) {
+	if os.Getenv("WHITESPACE_TEST_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// gitCommand returns a git command run in dir, isolated from the user's
+// configuration, that runs the test binary as the whitespace command
+func gitCommand(dir string, args ...string) *exec.Cmd {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"WHITESPACE_TEST_MAIN=1",
+		"GIT_CONFIG_GLOBAL=/dev/null",
+		"GIT_CONFIG_NOSYSTEM=1",
+	)
+	return cmd
+}
+
+// gitRepo creates a temporary repository, skipping the test without git
+func gitRepo(t *testing.T) func(args ...string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) string {
+		t.Helper()
+		out, err := gitCommand(dir, args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return string(out)
+	}
+	run("init", "-q")
+	t.Chdir(dir)
+	return run
+}
+
+func TestFilterClean(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	git := gitRepo(t)
+	git("config", "filter.ws.process", shellQuote(exe)+" filter --clean --exclude vendor/")
+	git("config", "filter.ws.required", "true")
+
+	files := map[string]string{
+		".gitattributes": "* filter=ws\n",
+		"main.go":        "package main  \n\nfunc main() {}\t\n\n\n",
+		"notes.md":       "line one  \nline two\n",
+		"no-newline.txt": "text",
+		"vendor/lib.go":  "package lib  \n",
+		"large.txt":      strings.Repeat("0123456789 \n", 20000),
+		"binary.bin":     "\x00\x01  \n",
+		"script.sh":      "cat <<EOF\nkeep  \nEOF\n",
+		"unchanged.txt":  "clean\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("add", ".")
+
+	expected := map[string]string{
+		"main.go":        "package main\n\nfunc main() {}\n",
+		"notes.md":       "line one  \nline two\n",
+		"no-newline.txt": "text\n",
+		"vendor/lib.go":  "package lib  \n",
+		"large.txt":      strings.Repeat("0123456789\n", 20000),
+		"binary.bin":     "\x00\x01  \n",
+		"script.sh":      "cat <<EOF\nkeep  \nEOF\n",
+		"unchanged.txt":  "clean\n",
+	}
+	for name, want := range expected {
+		if got := git("show", ":"+name); got != want {
+			t.Errorf("%s: expected staged %.40q, got %.40q", name, want, got)
+		}
+		// The filter changes what is staged, never the working tree
+		if data, err := os.ReadFile(name); err != nil {
+			t.Fatal(err)
+		} else if string(data) != files[name] {
+			t.Errorf("%s: working tree file was modified", name)
+		}
+	}
+}
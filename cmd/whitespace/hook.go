@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/scottrigby/whitespace-tools/internal/cli"
+	"github.com/scottrigby/whitespace-tools/internal/whitespace"
+)
+
+// hookMarker identifies a pre-commit hook written by 'whitespace hook install'
+const hookMarker = "# Managed by 'whitespace hook install'; remove with 'whitespace hook uninstall'."
+
+// hookConfig holds the options of 'whitespace hook run', which install
+// records in the hook script
+type hookConfig struct {
+	check   bool
+	backup  cli.BackupFlag
+	journal string
+	fixConfig
+}
+
+func (c *hookConfig) setupFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.check, "check", false, "report problems in staged files and abort the commit instead of fixing them")
+	fs.Var(&c.backup, "backup", "save a copy of each modified working tree file with the given suffix (default \"~\")")
+	fs.StringVar(&c.journal, "journal", "", "record modified working tree files in this directory for undo (default: $WHITESPACE_JOURNAL)")
+	c.fixConfig.setupFlags(fs)
+}
+
+// args returns the command line flags that reproduce c
+func (c *hookConfig) args() []string {
+	var args []string
+	if c.check {
+		args = append(args, "--check")
+	}
+	if c.backup != "" {
+		args = append(args, "--backup="+string(c.backup))
+	}
+	if c.journal != "" {
+		args = append(args, "--journal", c.journal)
+	}
+	return append(args, c.fixConfig.args()...)
+}
+
+// runHook implements: whitespace hook install|uninstall|run
+func runHook(args []string) error {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s hook install|uninstall|run [OPTIONS]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nManages a git pre-commit hook that fixes (or, with --check, reports)\n")
+		fmt.Fprintf(os.Stderr, "whitespace problems in staged files.\n\n")
+		fmt.Fprintf(os.Stderr, "  install\tInstall the hook; options are passed on to 'hook run'\n")
+		fmt.Fprintf(os.Stderr, "  uninstall\tRemove the hook if it was installed by 'hook install'\n")
+		fmt.Fprintf(os.Stderr, "  run\t\tProcess the staged files, as the hook does\n")
+	}
+	if len(args) == 0 {
+		usage()
+		return errors.New("missing hook command")
+	}
+	switch args[0] {
+	case "install":
+		return hookInstall(args[1:])
+	case "uninstall":
+		return hookUninstall(args[1:])
+	case "run":
+		return hookRun(args[1:])
+	case "-h", "--help", "help":
+		usage()
+		return nil
+	}
+	usage()
+	return fmt.Errorf("unknown hook command %q", args[0])
+}
+
+// hookPath returns the pre-commit hook path, honoring core.hooksPath
+func hookPath() (string, error) {
+	dir, err := git("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(filepath.Join(strings.TrimSpace(string(dir)), "pre-commit"))
+}
+
+// isManagedHook reports whether the hook at path was written by hookInstall
+func isManagedHook(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Contains(data, []byte(hookMarker)), nil
+}
+
+func hookInstall(args []string) error {
+	var cfg hookConfig
+	fs := flag.NewFlagSet("hook install", flag.ExitOnError)
+	cfg.setupFlags(fs)
+	force := fs.Bool("force", false, "replace an existing pre-commit hook not installed by whitespace")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return errors.New("too many arguments")
+	}
+	if _, err := whitespace.ParseRules(cfg.rules); err != nil {
+		return err
+	}
+
+	path, err := hookPath()
+	if err != nil {
+		return err
+	}
+	if managed, err := isManagedHook(path); err == nil && !managed && !*force {
+		return fmt.Errorf("%s already exists and was not installed by whitespace; use --force to replace it", path)
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	// Prefer the command on PATH so upgrades are picked up
+	exe := "whitespace"
+	if _, err := exec.LookPath(exe); err != nil {
+		if exe, err = os.Executable(); err != nil {
+			return err
+		}
+	}
+	command := []string{shellQuote(exe), "hook", "run"}
+	for _, a := range cfg.args() {
+		command = append(command, shellQuote(a))
+	}
+	script := "#!/bin/sh\n" + hookMarker + "\nexec " + strings.Join(command, " ") + "\n"
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of an existing file
+	if err := os.Chmod(path, 0o755); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Installed pre-commit hook %s\n", path)
+	return nil
+}
+
+func hookUninstall(args []string) error {
+	fs := flag.NewFlagSet("hook uninstall", flag.ExitOnError)
+	fs.Parse(args)
+	path, err := hookPath()
+	if err != nil {
+		return err
+	}
+	managed, err := isManagedHook(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no pre-commit hook at %s", path)
+	} else if err != nil {
+		return err
+	}
+	if !managed {
+		return fmt.Errorf("%s was not installed by whitespace; leaving it alone", path)
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Removed pre-commit hook %s\n", path)
+	return nil
+}
+
+// stagedFile is a regular file in the index
+type stagedFile struct {
+	path string
+	mode string
+	blob string
+}
+
+// stagedFiles lists the added, copied, modified and renamed files in the index
+func stagedFiles() ([]stagedFile, error) {
+	out, err := git("diff", "--cached", "--raw", "-z", "--no-abbrev", "--no-renames", "--diff-filter=ACMR")
+	if err != nil {
+		return nil, err
+	}
+	// Each entry is ":oldmode newmode oldsha newsha status" NUL path NUL
+	fields := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	var files []stagedFile
+	for i := 0; i+1 < len(fields); i += 2 {
+		meta := strings.Fields(fields[i])
+		if len(meta) < 5 {
+			return nil, fmt.Errorf("unexpected git diff output %q", fields[i])
+		}
+		// Skip symbolic links (120000) and submodules (160000)
+		if mode := meta[1]; mode == "100644" || mode == "100755" {
+			files = append(files, stagedFile{path: fields[i+1], mode: mode, blob: meta[3]})
+		}
+	}
+	return files, nil
+}
+
+// hookRun fixes the staged content of each selected file, stages the fix and
+// applies it to the working tree copy as well, or in check mode reports the
+// problems and fails so the commit is aborted
+func hookRun(args []string) (err error) {
+	var cfg hookConfig
+	fs := flag.NewFlagSet("hook run", flag.ExitOnError)
+	cfg.setupFlags(fs)
+	fs.Parse(args)
+	rules, err := whitespace.ParseRules(cfg.rules)
+	if err != nil {
+		return err
+	}
+	opts := cfg.options()
+	opts.Backup = string(cfg.backup)
+	journalDir := cfg.journal
+	if journalDir == "" {
+		journalDir = os.Getenv("WHITESPACE_JOURNAL")
+	}
+	if journalDir != "" && !cfg.check {
+		if opts.Journal, err = whitespace.NewJournal(journalDir); err != nil {
+			return err
+		}
+		defer func() {
+			j := opts.Journal
+			if closeErr := j.Close(); closeErr != nil {
+				err = errors.Join(err, closeErr)
+			} else if j.Count() > 0 {
+				fmt.Fprintf(os.Stderr, "whitespace: journal run %s recorded %d file(s); revert with: whitespace undo --journal %s %s\n", j.Run, j.Count(), j.Dir, j.Run)
+			}
+		}()
+	}
+
+	// Staged paths are relative to the top of the working tree
+	top, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(strings.TrimSpace(string(top))); err != nil {
+		return err
+	}
+	files, err := stagedFiles()
+	if err != nil {
+		return err
+	}
+
+	var findings []whitespace.Finding
+	fixed := 0
+	for _, f := range files {
+		content, err := git("cat-file", "blob", f.blob)
+		if err != nil {
+			return err
+		}
+		if ok, err := whitespace.SelectContent(f.path, content, opts); err != nil || !ok {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if cfg.check {
+			found, err := whitespace.CheckContent(f.path, content, rules, opts)
+			if err != nil {
+				return fmt.Errorf("%s: %w", f.path, err)
+			}
+			findings = append(findings, found...)
+			continue
+		}
+
+		changed, err := fixStaged(f, content, rules, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.path, err)
+		}
+		if changed {
+			fmt.Fprintf(os.Stderr, "whitespace: fixed %s\n", f.path)
+			fixed++
+		}
+	}
+
+	if len(findings) > 0 {
+		if err := whitespace.WriteReport(os.Stdout, whitespace.FormatText, whitespace.Report{Findings: findings}); err != nil {
+			return err
+		}
+		return fmt.Errorf("%d whitespace problem(s) in staged files; commit aborted\n"+
+			"Fix them and stage the result (or run 'whitespace hook run'), or bypass the check with 'git commit --no-verify'", len(findings))
+	}
+	if fixed > 0 {
+		fmt.Fprintf(os.Stderr, "whitespace: fixed and re-staged %d file(s)\n", fixed)
+	}
+	return nil
+}
+
+// fixStaged writes the fixed form of a staged blob to the index and applies
+// the same fix to the working tree file, whose unstaged changes are kept
+func fixStaged(f stagedFile, content []byte, rules []string, opts whitespace.Options) (bool, error) {
+	fixedContent, err := whitespace.FixContent(f.path, content, rules, opts)
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(fixedContent, content) {
+		return false, nil
+	}
+	blob, err := gitInput(fixedContent, "hash-object", "-w", "--stdin", "--no-filters")
+	if err != nil {
+		return false, err
+	}
+	cacheinfo := f.mode + "," + strings.TrimSpace(string(blob)) + "," + f.path
+	if _, err := git("update-index", "--cacheinfo", cacheinfo); err != nil {
+		return false, err
+	}
+
+	// The working tree copy is fixed like the tools fix files, so its mode,
+	// hard links, backup and journal entry are handled the same way. A path
+	// that is gone or no longer a regular file, such as a symlink to a file
+	// outside the repository, is left alone.
+	info, err := os.Lstat(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return true, nil
+	} else if err != nil {
+		return true, err
+	}
+	if !info.Mode().IsRegular() {
+		return true, nil
+	}
+	_, err = whitespace.FixFile(f.path, rules, opts)
+	return true, err
+}
+
+// git runs a git command and returns its standard output
+func git(args ...string) ([]byte, error) {
+	return gitInput(nil, args...)
+}
+
+// gitInput runs a git command with input on standard input
+func gitInput(input []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	if input != nil {
+		cmd.Stdin = bytes.NewReader(input)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
+
+// shellQuote quotes s for a POSIX shell script
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./,=:") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scottrigby/whitespace-tools/internal/whitespace"
+)
+
+// hookRepo creates a repository for hook tests, with the test binary as
+// the whitespace command the hook runs
+func hookRepo(t *testing.T) func(args ...string) string {
+	t.Helper()
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git not available")
+	}
+	// Keep an installed whitespace command out of the hook script
+	t.Setenv("PATH", filepath.Dir(gitPath))
+	if _, err := exec.LookPath("whitespace"); err == nil {
+		t.Skip("whitespace is installed next to git")
+	}
+	git := gitRepo(t)
+	git("config", "user.name", "Test")
+	git("config", "user.email", "test@example.com")
+	return git
+}
+
+func writeFile(t *testing.T, name, content string, perm os.FileMode) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), perm); err != nil {
+		t.Fatal(err)
+	}
+	// WriteFile keeps the mode of an existing file
+	if err := os.Chmod(name, perm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestHookInstall(t *testing.T) {
+	hookRepo(t)
+	path := filepath.Join(".git", "hooks", "pre-commit")
+
+	if err := runHook([]string{"install", "--check", "--exclude", "docs/"}); err != nil {
+		t.Fatal(err)
+	}
+	script := readFile(t, path)
+	if !strings.Contains(script, hookMarker) || !strings.Contains(script, "hook run --check --exclude docs/") {
+		t.Errorf("unexpected hook script:\n%s", script)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm()&0o111 == 0 {
+		t.Errorf("expected an executable hook, got %v, %v", info, err)
+	}
+	// Installing again replaces our own hook
+	if err := runHook([]string{"install"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runHook([]string{"uninstall"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the hook to be removed, got %v", err)
+	}
+	if err := runHook([]string{"uninstall"}); err == nil {
+		t.Error("expected an error uninstalling a missing hook")
+	}
+
+	// A hook installed by something else is left alone
+	foreign := "#!/bin/sh\necho mine\n"
+	writeFile(t, path, foreign, 0o755)
+	if err := runHook([]string{"install"}); err == nil {
+		t.Error("expected install to refuse to replace a foreign hook")
+	}
+	if err := runHook([]string{"uninstall"}); err == nil {
+		t.Error("expected uninstall to refuse to remove a foreign hook")
+	}
+	if got := readFile(t, path); got != foreign {
+		t.Errorf("foreign hook was modified:\n%s", got)
+	}
+	if err := runHook([]string{"install", "--force"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(readFile(t, path), hookMarker) {
+		t.Error("expected --force to replace the foreign hook")
+	}
+}
+
+func TestHookInstall_HooksPath(t *testing.T) {
+	git := hookRepo(t)
+	git("config", "core.hooksPath", "githooks")
+	if err := runHook([]string{"install"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(readFile(t, filepath.Join("githooks", "pre-commit")), hookMarker) {
+		t.Error("expected the hook in core.hooksPath")
+	}
+	if _, err := os.Stat(filepath.Join(".git", "hooks", "pre-commit")); !os.IsNotExist(err) {
+		t.Errorf("expected no hook in .git/hooks, got %v", err)
+	}
+}
+
+func TestHookCommit(t *testing.T) {
+	git := hookRepo(t)
+	journalDir := filepath.Join(t.TempDir(), "journal")
+	if err := runHook([]string{"install", "--journal", journalDir}); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, "main.go", "package main  \n\nfunc main() {}", 0o644)
+	writeFile(t, "run.sh", "#!/bin/sh\necho hi \t\n", 0o755)
+	writeFile(t, "clean.txt", "clean\n", 0o644)
+	writeFile(t, "vendor/lib.go", "package lib  \n", 0o644)
+	git("add", ".")
+	// An unstaged change is kept, and fixed along with the staged content
+	writeFile(t, "main.go", "package main  \n\nfunc main() {}\n\n// todo  \n", 0o644)
+	git("commit", "-q", "-m", "add files")
+
+	committed := map[string]string{
+		"main.go":       "package main\n\nfunc main() {}\n",
+		"run.sh":        "#!/bin/sh\necho hi\n",
+		"clean.txt":     "clean\n",
+		"vendor/lib.go": "package lib  \n",
+	}
+	for name, want := range committed {
+		if got := git("show", "HEAD:"+name); got != want {
+			t.Errorf("%s: expected committed %q, got %q", name, want, got)
+		}
+	}
+	worktree := map[string]string{
+		"main.go":       "package main\n\nfunc main() {}\n\n// todo\n",
+		"run.sh":        "#!/bin/sh\necho hi\n",
+		"clean.txt":     "clean\n",
+		"vendor/lib.go": "package lib  \n",
+	}
+	for name, want := range worktree {
+		if got := readFile(t, name); got != want {
+			t.Errorf("%s: expected working tree %q, got %q", name, want, got)
+		}
+	}
+
+	// The index matches the commit, and only the unstaged change remains
+	if out := git("diff", "--cached", "--name-only"); out != "" {
+		t.Errorf("expected nothing left staged, got %q", out)
+	}
+	if out := git("diff", "--name-only"); out != "main.go\n" {
+		t.Errorf("expected only main.go to differ from the index, got %q", out)
+	}
+	if mode := git("ls-files", "-s", "run.sh"); !strings.HasPrefix(mode, "100755 ") {
+		t.Errorf("expected run.sh to stay executable in the index, got %q", mode)
+	}
+	if info, err := os.Stat("run.sh"); err != nil || info.Mode().Perm() != 0o755 {
+		t.Errorf("expected run.sh to keep mode 0755, got %v, %v", info, err)
+	}
+
+	// The working tree rewrites were journaled, so they can be undone
+	runs, err := whitespace.JournalRuns(journalDir)
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("expected one journal run, got %v, %v", runs, err)
+	}
+	if _, _, err := whitespace.Undo(journalDir, "", false); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, "main.go"); got != "package main  \n\nfunc main() {}\n\n// todo  \n" {
+		t.Errorf("expected undo to restore main.go, got %q", got)
+	}
+}
+
+func TestHookCommit_WorktreeSymlink(t *testing.T) {
+	git := hookRepo(t)
+	if err := runHook([]string{"install"}); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, "main.go", "package main  \n", 0o644)
+	git("add", ".")
+
+	// The staged file is replaced by a link to a file outside the repository
+	outside := filepath.Join(t.TempDir(), "outside.go")
+	writeFile(t, outside, "package outside  \n", 0o644)
+	if err := os.Remove("main.go"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, "main.go"); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	git("commit", "-q", "-m", "add files")
+
+	if got := git("show", "HEAD:main.go"); got != "package main\n" {
+		t.Errorf("expected the staged content to be fixed, got %q", got)
+	}
+	if got := readFile(t, outside); got != "package outside  \n" {
+		t.Errorf("expected the link target to be left alone, got %q", got)
+	}
+	if info, err := os.Lstat("main.go"); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("expected main.go to stay a symlink, got %v, %v", info, err)
+	}
+}
+
+func TestHookCommit_Check(t *testing.T) {
+	git := hookRepo(t)
+	if err := runHook([]string{"install", "--check"}); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, "main.go", "package main  \n", 0o644)
+	git("add", ".")
+
+	out, err := gitCommand(".", "commit", "-q", "-m", "add files").CombinedOutput()
+	if err == nil {
+		t.Fatalf("expected the commit to be aborted, got\n%s", out)
+	}
+	for _, want := range []string{"main.go:1:13: trailingspace: trailing whitespace", "commit aborted"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("expected %q in the output, got\n%s", want, out)
+		}
+	}
+	if got := git("show", ":main.go"); got != "package main  \n" {
+		t.Errorf("check mode changed the staged content: %q", got)
+	}
+	if got := readFile(t, "main.go"); got != "package main  \n" {
+		t.Errorf("check mode changed the working tree: %q", got)
+	}
+}
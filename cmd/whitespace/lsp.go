@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/scottrigby/whitespace-tools/internal/lsp"
+	"github.com/scottrigby/whitespace-tools/internal/whitespace"
+)
+
+// runLSP implements: whitespace lsp [OPTIONS]
+func runLSP(args []string) error {
+	var cfg fixConfig
+	fs := flag.NewFlagSet("lsp", flag.ExitOnError)
+	cfg.setupFlags(fs)
+	// Many editors pass --stdio; it is the only transport, so it is accepted and ignored
+	fs.Bool("stdio", true, "communicate over standard input and output (the default)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s lsp [OPTIONS]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nA Language Server Protocol server over standard input and output.\n")
+		fmt.Fprintf(os.Stderr, "Open documents get whitespace diagnostics with quick fixes, and\n")
+		fmt.Fprintf(os.Stderr, "document formatting applies every fix.\n\n")
+		fmt.Fprintf(os.Stderr, "OPTIONS:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return errors.New("too many arguments")
+	}
+	rules, err := whitespace.ParseRules(cfg.rules)
+	if err != nil {
+		return err
+	}
+	return lsp.Serve(os.Stdin, os.Stdout, rules, cfg.options(), os.Stderr)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/scottrigby/whitespace-tools/internal/cli"
+)
+
+var (
+	// injected by ldflags:
+	// -X main.version
+	// -X main.commit
+	version string
+	commit  string
+)
+
+// command is a whitespace subcommand
+type command struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
+
+var commands = []command{
+	{"filter", "Git filter driver that fixes content as it is staged", runFilter},
+	{"hook", "Install, remove or run the git pre-commit hook", runHook},
+	{"lsp", "Language server reporting and fixing whitespace in an editor", runLSP},
+	{"undo", "Restore files modified by a journaled run", runUndo},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s COMMAND [OPTIONS]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "\nCompanion commands for newline and trailingspace.\n\n")
+	fmt.Fprintf(os.Stderr, "COMMANDS:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %s\t\t\t%s\n", c.name, c.description)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s COMMAND --help' for command options.\n", os.Args[0])
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	switch name {
+	case "-v", "--version", "version":
+		cli.HandleVersion(true, "whitespace", version, commit)
+		return
+	case "-h", "--help", "help":
+		usage()
+		return
+	}
+
+	for _, c := range commands {
+		if c.name == name {
+			cli.HandleError(c.run(os.Args[2:]))
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Error: unknown command %q\n", name)
+	usage()
+	os.Exit(2)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/scottrigby/whitespace-tools/internal/whitespace"
+)
+
+// runUndo implements: whitespace undo [--journal DIR] [--list] [--force] [RUN]
+func runUndo(args []string) error {
+	fs := flag.NewFlagSet("undo", flag.ExitOnError)
+	dir := fs.String("journal", os.Getenv("WHITESPACE_JOURNAL"), "journal directory (default: $WHITESPACE_JOURNAL)")
+	list := fs.Bool("list", false, "list runs that can be undone, oldest first")
+	force := fs.Bool("force", false, "restore files even if they changed after the run")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s undo [OPTIONS] [RUN]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nRestores every file modified by RUN (default: the latest run not yet undone).\n\n")
+		fmt.Fprintf(os.Stderr, "OPTIONS:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *dir == "" {
+		return errors.New("no journal directory: use --journal DIR or set WHITESPACE_JOURNAL")
+	}
+	if fs.NArg() > 1 {
+		return errors.New("too many arguments")
+	}
+
+	if *list {
+		runs, err := whitespace.JournalRuns(*dir)
+		if err != nil {
+			return err
+		}
+		for _, run := range runs {
+			fmt.Println(run)
+		}
+		return nil
+	}
+
+	run, restored, err := whitespace.Undo(*dir, fs.Arg(0), *force)
+	for _, path := range restored {
+		fmt.Println("restored", path)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Undid run %s (%d file(s))\n", run, len(restored))
+	return nil
+}
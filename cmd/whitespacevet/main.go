@@ -0,0 +1,16 @@
+// Command whitespacevet runs the whitespace analyzer on Go packages, on its
+// own or as a go vet tool:
+//
+//	whitespacevet ./...
+//	go vet -vettool=$(command -v whitespacevet) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/scottrigby/whitespace-tools/analyzer"
+)
+
+func main() {
+	singlechecker.Main(analyzer.Analyzer)
+}
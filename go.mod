@@ -2,4 +2,13 @@ module github.com/scottrigby/whitespace-tools
 
 go 1.24.3
 
-require github.com/gobwas/glob v0.2.3
+require (
+	github.com/fsnotify/fsnotify v1.9.0
+	golang.org/x/tools v0.42.0
+)
+
+require (
+	golang.org/x/mod v0.33.0 // indirect
+	golang.org/x/sync v0.19.0 // indirect
+	golang.org/x/sys v0.41.0 // indirect
+)
@@ -1,9 +1,21 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
 	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/scottrigby/whitespace-tools/internal/whitespace"
 )
 
 // ArrayFlags implements flag.Value for multiple string values
@@ -18,11 +30,65 @@ func (i *ArrayFlags) Set(value string) error {
 	return nil
 }
 
+// BackupFlag implements flag.Value for --backup[=suffix]
+type BackupFlag string
+
+func (b *BackupFlag) String() string {
+	return string(*b)
+}
+
+func (b *BackupFlag) Set(value string) error {
+	// A bare --backup is reported as "true" because IsBoolFlag is set
+	if value == "true" {
+		value = whitespace.DefaultBackupSuffix
+	} else if value == "false" {
+		value = ""
+	}
+	*b = BackupFlag(value)
+	return nil
+}
+
+func (b *BackupFlag) IsBoolFlag() bool {
+	return true
+}
+
 // CommonFlags holds shared CLI flags and provides common setup
 type CommonFlags struct {
-	IncludeHidden   bool
-	ExcludePatterns ArrayFlags
-	ShowVersion     bool
+	IncludeHidden       bool
+	ExcludePatterns     ArrayFlags
+	IncludePatterns     ArrayFlags
+	Types               ArrayFlags
+	NoDefaultExcludes   bool
+	IncludeGenerated    bool
+	ListDefaultExcludes bool
+	KeepGoing           bool
+	Symlinks            string
+	Hardlinks           string
+	Backup              BackupFlag
+	JournalDir          string
+	TextDetect          string
+	LegacyEncoding      string
+	Force               bool
+	Markdown            string
+	HardBreaks          string
+	CodeBlocks          string
+	FailOnChange        bool
+	Watch               bool
+	Check               bool
+	Baseline            string
+	WriteBaseline       string
+	Format              string
+	Verbose             bool
+	Quiet               bool
+	LogFormat           string
+	ShowVersion         bool
+
+	mu       sync.Mutex        // Guards the counts, as watched targets are processed concurrently
+	modified int               // Files modified so far, for FailOnChange and the summary
+	scanned  int               // Files checked or fixed so far, for the summary
+	skipped  int               // Files passed over so far, for the summary
+	format   whitespace.Format // Parsed Format
+	checked  []string          // Files checked so far, for the report
 }
 
 // SetupFlags sets up the standard flags for both tools
@@ -31,31 +97,264 @@ func (cf *CommonFlags) SetupFlags() {
 	flag.BoolVar(&cf.IncludeHidden, "i", false, "process files in hidden directories recursively (short form)")
 	flag.Var(&cf.ExcludePatterns, "exclude", "exclude files/directories matching glob pattern (can be used multiple times)")
 	flag.Var(&cf.ExcludePatterns, "e", "exclude files/directories matching glob pattern (short form)")
+	flag.Var(&cf.IncludePatterns, "include", "only process files matching glob pattern (can be used multiple times)")
+	flag.Var(&cf.Types, "type", "only process files of these types, comma separated (e.g. go,yaml,md)")
+	flag.Var(&cf.Types, "t", "only process files of these types (short form)")
+	flag.BoolVar(&cf.NoDefaultExcludes, "no-default-excludes", false, "do not apply the built-in exclude patterns")
+	flag.BoolVar(&cf.IncludeGenerated, "include-generated", false, "process files marked \"Code generated ... DO NOT EDIT.\"")
+	flag.BoolVar(&cf.ListDefaultExcludes, "list-default-excludes", false, "print the built-in exclude patterns and exit")
+	flag.BoolVar(&cf.KeepGoing, "keep-going", false, "continue past per-file errors and report them at the end")
+	flag.BoolVar(&cf.KeepGoing, "k", false, "continue past per-file errors and report them at the end (short form)")
+	flag.StringVar(&cf.Symlinks, "symlinks", "skip", "symbolic link policy: skip, follow or follow-within-root")
+	flag.StringVar(&cf.Hardlinks, "hardlinks", "preserve", "hard-linked file policy: preserve, warn or skip")
+	flag.Var(&cf.Backup, "backup", "save a copy of each modified file with the given suffix (default \"~\")")
+	flag.StringVar(&cf.JournalDir, "journal", os.Getenv("WHITESPACE_JOURNAL"), "record original content of modified files in this directory for undo")
+	flag.StringVar(&cf.TextDetect, "text-detect", "heuristic", "text detection mode: heuristic, extension or strict")
+	flag.StringVar(&cf.LegacyEncoding, "legacy-encoding", "", "single-byte encoding for text that is not valid UTF-8: latin1 or windows-1252")
+	flag.BoolVar(&cf.Force, "force", false, "process an explicitly named file even if it does not look like text")
+	flag.BoolVar(&cf.Force, "f", false, "process an explicitly named file even if it does not look like text (short form)")
+	flag.BoolVar(&cf.FailOnChange, "fail-on-change", false, "exit 1 if any file was modified, as the pre-commit framework expects")
+	flag.BoolVar(&cf.Watch, "watch", false, "keep running, fixing files as they are created or saved, until interrupted")
+	flag.BoolVar(&cf.Check, "check", false, "report problems without modifying files, exiting 1 if any are found")
+	flag.StringVar(&cf.Baseline, "baseline", "", "check mode, only reporting problems not recorded in this baseline file")
+	flag.StringVar(&cf.WriteBaseline, "write-baseline", "", "record current problems in this baseline file without modifying files")
+	flag.StringVar(&cf.Format, "format", "text", "output format for reported problems: text, checkstyle, junit, github or gitlab")
+	flag.BoolVar(&cf.Verbose, "verbose", false, "log why each file was skipped, fixed or left unchanged")
+	flag.BoolVar(&cf.Verbose, "V", false, "log why each file was skipped, fixed or left unchanged (short form)")
+	flag.BoolVar(&cf.Quiet, "quiet", false, "only print problems and errors")
+	flag.BoolVar(&cf.Quiet, "q", false, "only print problems and errors (short form)")
+	flag.StringVar(&cf.LogFormat, "log-format", "text", "format of --verbose logs: text or json")
 	flag.BoolVar(&cf.ShowVersion, "version", false, "show version information")
 	flag.BoolVar(&cf.ShowVersion, "v", false, "show version information (short form)")
 }
 
+// SetupMarkdownFlags sets up the Markdown flags, which only trailingspace uses
+func (cf *CommonFlags) SetupMarkdownFlags() {
+	flag.StringVar(&cf.Markdown, "markdown", "auto", "apply Markdown hard line break rules: auto (.md files), always or never")
+	flag.StringVar(&cf.HardBreaks, "markdown-hard-breaks", "preserve", "Markdown hard line breaks: preserve (two spaces) or backslash")
+	flag.StringVar(&cf.CodeBlocks, "markdown-code-blocks", "trim", "fenced Markdown code blocks: trim or preserve")
+}
+
+// splitList flattens repeated, comma separated flag values
+func splitList(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				out = append(out, item)
+			}
+		}
+	}
+	return out
+}
+
+// CheckMode reports whether files are checked rather than fixed
+func (cf *CommonFlags) CheckMode() bool {
+	return cf.Check || cf.Baseline != "" || cf.WriteBaseline != ""
+}
+
+// Options converts the parsed flags into processing options. If a journal
+// directory was given a new journal run is started; pass the options to
+// Finish when processing is done.
+func (cf *CommonFlags) Options() (whitespace.Options, error) {
+	if cf.Baseline != "" && cf.WriteBaseline != "" {
+		return whitespace.Options{}, errors.New("--baseline and --write-baseline cannot be combined")
+	}
+	if cf.Watch && cf.CheckMode() {
+		return whitespace.Options{}, errors.New("--watch cannot be combined with --check, --baseline or --write-baseline")
+	}
+	symlinks, err := whitespace.ParseSymlinkPolicy(cf.Symlinks)
+	if err != nil {
+		return whitespace.Options{}, err
+	}
+	hardlinks, err := whitespace.ParseHardlinkPolicy(cf.Hardlinks)
+	if err != nil {
+		return whitespace.Options{}, err
+	}
+	textDetect, err := whitespace.ParseTextDetect(cf.TextDetect)
+	if err != nil {
+		return whitespace.Options{}, err
+	}
+	legacy, err := whitespace.ParseLegacyEncoding(cf.LegacyEncoding)
+	if err != nil {
+		return whitespace.Options{}, err
+	}
+	markdown, err := whitespace.ParseMarkdownMode(cf.Markdown)
+	if err != nil {
+		return whitespace.Options{}, err
+	}
+	hardBreaks, err := whitespace.ParseHardBreakStyle(cf.HardBreaks)
+	if err != nil {
+		return whitespace.Options{}, err
+	}
+	codeBlocks, err := whitespace.ParseCodeBlockPolicy(cf.CodeBlocks)
+	if err != nil {
+		return whitespace.Options{}, err
+	}
+	if cf.format, err = whitespace.ParseFormat(cf.Format); err != nil {
+		return whitespace.Options{}, err
+	}
+	if cf.format != whitespace.FormatText && (!cf.CheckMode() || cf.WriteBaseline != "") {
+		return whitespace.Options{}, errors.New("--format only applies to problems reported by --check or --baseline")
+	}
+	if cf.Verbose && cf.Quiet {
+		return whitespace.Options{}, errors.New("--verbose and --quiet cannot be combined")
+	}
+	logger, err := newLogger(cf.LogFormat, cf.Verbose)
+	if err != nil {
+		return whitespace.Options{}, err
+	}
+	var warn io.Writer = os.Stderr
+	if cf.Quiet {
+		warn = nil
+	}
+	opts := whitespace.Options{
+		IncludeHidden:     cf.IncludeHidden,
+		ExcludePatterns:   []string(cf.ExcludePatterns),
+		IncludePatterns:   []string(cf.IncludePatterns),
+		Types:             splitList(cf.Types),
+		NoDefaultExcludes: cf.NoDefaultExcludes,
+		IncludeGenerated:  cf.IncludeGenerated,
+		KeepGoing:         cf.KeepGoing,
+		Symlinks:          symlinks,
+		Hardlinks:         hardlinks,
+		Warn:              warn,
+		Log:               logger,
+		Modified: func(path string) {
+			cf.mu.Lock()
+			defer cf.mu.Unlock()
+			cf.modified++
+			if !cf.Quiet {
+				fmt.Println("Fixed", path)
+			}
+		},
+		Checked: func(path string) {
+			cf.mu.Lock()
+			defer cf.mu.Unlock()
+			cf.scanned++
+			if cf.CheckMode() {
+				cf.checked = append(cf.checked, path)
+			}
+		},
+		Skipped: func(path string) {
+			cf.mu.Lock()
+			defer cf.mu.Unlock()
+			cf.skipped++
+		},
+		Backup:         string(cf.Backup),
+		TextDetect:     textDetect,
+		LegacyEncoding: legacy,
+		Force:          cf.Force,
+		Markdown:       markdown,
+		HardBreaks:     hardBreaks,
+		CodeBlocks:     codeBlocks,
+	}
+	if cf.JournalDir != "" && !cf.CheckMode() {
+		if opts.Journal, err = whitespace.NewJournal(cf.JournalDir); err != nil {
+			return whitespace.Options{}, err
+		}
+	}
+	return opts, nil
+}
+
+// newLogger returns the logger for --verbose in the --log-format format,
+// or nil without --verbose
+func newLogger(format string, verbose bool) (*slog.Logger, error) {
+	handlerOpts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var handler slog.Handler
+	switch format {
+	case "", "text":
+		// Times add nothing to the log of a run read in a terminal
+		handlerOpts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		}
+		handler = slog.NewTextHandler(os.Stderr, handlerOpts)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, handlerOpts)
+	default:
+		return nil, fmt.Errorf("invalid log format %q (want text or json)", format)
+	}
+	if !verbose {
+		return nil, nil
+	}
+	return slog.New(handler), nil
+}
+
+// infof writes a progress message to stderr, unless --quiet is set
+func (cf *CommonFlags) infof(format string, args ...any) {
+	if !cf.Quiet {
+		fmt.Fprintf(os.Stderr, format, args...)
+	}
+}
+
 // SetupUsage sets up the standard usage function for both tools
 func SetupUsage(description string) {
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] [target]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] [target...]\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "\n%s\n\n", description)
 		fmt.Fprintf(os.Stderr, "OPTIONS:\n")
 		fmt.Fprintf(os.Stderr, "  -i, --include-hidden\t\tProcess files in hidden directories recursively\n")
 		fmt.Fprintf(os.Stderr, "  -e, --exclude PATTERN\t\tExclude files/directories matching glob pattern\n")
+		fmt.Fprintf(os.Stderr, "  --include PATTERN\t\tOnly process files matching glob pattern\n")
+		fmt.Fprintf(os.Stderr, "  -t, --type TYPES\t\tOnly process files of these types (%s)\n", strings.Join(whitespace.FileTypes(), ","))
+		fmt.Fprintf(os.Stderr, "  --no-default-excludes\t\tDo not apply the built-in exclude patterns\n")
+		fmt.Fprintf(os.Stderr, "  --list-default-excludes\tPrint the built-in exclude patterns and exit\n")
+		fmt.Fprintf(os.Stderr, "  --include-generated\t\tProcess files marked \"Code generated ... DO NOT EDIT.\"\n")
+		fmt.Fprintf(os.Stderr, "  -k, --keep-going\t\tContinue past per-file errors and report them at the end\n")
+		fmt.Fprintf(os.Stderr, "  --symlinks POLICY\t\tSymbolic links: skip (default), follow, follow-within-root\n")
+		fmt.Fprintf(os.Stderr, "  --hardlinks POLICY\t\tHard-linked files: preserve (default, in-place write), warn, skip\n")
+		fmt.Fprintf(os.Stderr, "  --backup[=SUFFIX]\t\tSave a copy of each modified file as FILE~ (or FILE+SUFFIX)\n")
+		fmt.Fprintf(os.Stderr, "  --journal DIR\t\t\tRecord modified files for 'whitespace undo' (default: $WHITESPACE_JOURNAL)\n")
+		fmt.Fprintf(os.Stderr, "  --text-detect MODE\t\tText detection: heuristic (default), extension, strict\n")
+		fmt.Fprintf(os.Stderr, "  --legacy-encoding ENC\t\tTreat non-UTF-8 text as latin1 or windows-1252\n")
+		fmt.Fprintf(os.Stderr, "  -f, --force\t\t\tProcess a named file even if it does not look like text\n")
+		if flag.Lookup("markdown") != nil {
+			fmt.Fprintf(os.Stderr, "  --markdown MODE\t\tMarkdown hard line breaks: auto (default, .md files), always, never\n")
+			fmt.Fprintf(os.Stderr, "  --markdown-hard-breaks STYLE\tpreserve (default, two spaces) or backslash\n")
+			fmt.Fprintf(os.Stderr, "  --markdown-code-blocks POLICY\tFenced code blocks: trim (default) or preserve\n")
+		}
+		fmt.Fprintf(os.Stderr, "  --fail-on-change\t\tExit 1 if any file was modified (for the pre-commit framework)\n")
+		fmt.Fprintf(os.Stderr, "  --watch\t\t\tKeep fixing files as they are created or saved, until interrupted\n")
+		fmt.Fprintf(os.Stderr, "  --check\t\t\tReport problems without modifying files (exit 1 if any)\n")
+		fmt.Fprintf(os.Stderr, "  --baseline FILE\t\tLike --check, but only report problems not in FILE\n")
+		fmt.Fprintf(os.Stderr, "  --write-baseline FILE\t\tRecord current problems in FILE without modifying files\n")
+		fmt.Fprintf(os.Stderr, "  --format FORMAT\t\tReport problems as text (default), checkstyle, junit, github or gitlab\n")
+		fmt.Fprintf(os.Stderr, "  -V, --verbose\t\t\tLog why each file was skipped, fixed or left unchanged\n")
+		fmt.Fprintf(os.Stderr, "  -q, --quiet\t\t\tOnly print problems and errors\n")
+		fmt.Fprintf(os.Stderr, "  --log-format FORMAT\t\tFormat of --verbose logs: text (default) or json\n")
 		fmt.Fprintf(os.Stderr, "  -v, --version\t\t\tShow version information\n")
 		fmt.Fprintf(os.Stderr, "\nARGUMENTS:\n")
-		fmt.Fprintf(os.Stderr, "  target\t\t\tFile or directory to process (default: current directory)\n\n")
+		fmt.Fprintf(os.Stderr, "  target\t\t\tFiles or directories to process (default: current directory)\n\n")
 		fmt.Fprintf(os.Stderr, "\nBEHAVIOR:\n")
 		fmt.Fprintf(os.Stderr, "  Processes all text files recursively, skipping:\n")
 		fmt.Fprintf(os.Stderr, "  • Hidden directories (unless --include-hidden used)\n")
-		fmt.Fprintf(os.Stderr, "  • Non-text files (detected by heuristic)\n")
-		fmt.Fprintf(os.Stderr, "  • Files/directories matching --exclude patterns\n\n")
+		fmt.Fprintf(os.Stderr, "  • Non-text files (detected by magic number, BOM and heuristic; see --text-detect)\n")
+		fmt.Fprintf(os.Stderr, "  • Vendored, lockfile, minified and golden content (see --list-default-excludes)\n")
+		fmt.Fprintf(os.Stderr, "  • Generated files (unless --include-generated used)\n")
+		fmt.Fprintf(os.Stderr, "  • Files/directories matching --exclude patterns\n")
+		fmt.Fprintf(os.Stderr, "  • Symbolic links (unless --symlinks follow or follow-within-root used)\n")
+		fmt.Fprintf(os.Stderr, "  • FIFOs, sockets and device files\n\n")
+		fmt.Fprintf(os.Stderr, "  A file named as the target goes through the same checks (except hidden\n")
+		fmt.Fprintf(os.Stderr, "  directories); its patterns are matched relative to the working directory.\n\n")
+		fmt.Fprintf(os.Stderr, "  Patterns are relative to the target and follow .gitignore rules: a leading\n")
+		fmt.Fprintf(os.Stderr, "  '/' anchors to the root, '**' spans directories, a trailing '/' matches\n")
+		fmt.Fprintf(os.Stderr, "  directories only and a leading '!' negates.\n")
 		fmt.Fprintf(os.Stderr, "\nEXAMPLES:\n")
 		fmt.Fprintf(os.Stderr, "  %s file.txt\t\t\t\t# Process single file\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s src/\t\t\t\t\t# Process all text files in src/\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s --include-hidden\t\t\t# Include hidden directories\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s --exclude 'bin' --exclude '*.tmp'\t# Exclude patterns\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --type go,yaml --include '*.tpl'\t# Only Go, YAML and template files\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --keep-going .\t\t\t# Report all failures instead of stopping at the first\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --watch src/\t\t\t\t# Fix files in src/ as they are saved\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --journal ~/.whitespace .\t\t# Make this run undoable with 'whitespace undo'\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --write-baseline .whitespace-baseline.json .\t# Accept existing problems\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --baseline .whitespace-baseline.json .\t# Fail only on new problems\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --check --format junit . > report.xml\t# Report for CI\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --verbose .\t\t\t\t# Show why files were skipped\n", os.Args[0])
 	}
 }
 
@@ -75,16 +374,197 @@ func HandleVersion(showVersion bool, toolName, version, commit string) bool {
 	return true
 }
 
-// ParseTarget handles target argument parsing with validation
-func ParseTarget() (string, error) {
-	target := "."
-	if flag.NArg() > 0 {
-		target = flag.Arg(0)
+// FinishCheck reports the findings of a check-mode run for rule and exits
+// 1 if any remain after applying --baseline. With --write-baseline the
+// findings are recorded instead, replacing earlier entries for the same rule.
+func (cf *CommonFlags) FinishCheck(rule string, findings []whitespace.Finding, err error) {
+	if cf.WriteBaseline != "" {
+		// A partial baseline would hide problems in the files that failed
+		HandleError(err)
+		b, readErr := whitespace.ReadBaseline(cf.WriteBaseline)
+		if errors.Is(readErr, fs.ErrNotExist) {
+			b = whitespace.NewBaseline(nil)
+		} else {
+			HandleError(readErr)
+		}
+		b.Replace(rule, findings)
+		HandleError(b.WriteFile(cf.WriteBaseline))
+		cf.infof("Recorded %d %s problem(s) in %s\n", len(findings), rule, cf.WriteBaseline)
+		return
+	}
+	if cf.Baseline != "" {
+		b, readErr := whitespace.ReadBaseline(cf.Baseline)
+		HandleError(readErr)
+		findings = b.Filter(findings)
+	}
+	report := whitespace.Report{Rule: rule, Files: cf.checked, Findings: findings, Color: useColor(os.Stdout)}
+	HandleError(whitespace.WriteReport(os.Stdout, cf.format, report))
+	if len(findings) > 0 {
+		cf.infof("%d problem(s) found\n", len(findings))
+	}
+	failing := make(map[string]bool)
+	for _, f := range findings {
+		failing[f.Path] = true
+	}
+	cf.summary(len(failing), "with problems")
+	HandleError(err)
+	if len(findings) > 0 {
+		os.Exit(1)
+	}
+}
+
+// summary reports on stderr how many files were scanned, changed (as
+// described by changed) and skipped, unless --quiet is set
+func (cf *CommonFlags) summary(n int, changed string) {
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+	cf.infof("%d file(s) scanned, %d %s, %d skipped\n", cf.scanned, n, changed, cf.skipped)
+}
+
+// useColor reports whether output to f should be colored: f must be a
+// terminal, and NO_COLOR (https://no-color.org) must be unset or empty
+func useColor(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&fs.ModeCharDevice != 0
+}
+
+// HandleListDefaultExcludes prints the built-in exclude patterns if requested
+func HandleListDefaultExcludes(list bool) bool {
+	if !list {
+		return false
+	}
+	fmt.Printf("# default excludes, version %d\n", whitespace.DefaultExcludesVersion)
+	for _, p := range whitespace.DefaultExcludes {
+		fmt.Println(p)
+	}
+	return true
+}
+
+// ParseTargets returns the target arguments, defaulting to the current directory
+func ParseTargets() []string {
+	if flag.NArg() == 0 {
+		return []string{"."}
+	}
+	return flag.Args()
+}
+
+// ProcessTargets runs process on each target in turn. Without --keep-going
+// it stops at the first failure; otherwise the errors are combined.
+func ProcessTargets(targets []string, opts whitespace.Options, process func(string, whitespace.Options) error) error {
+	var errs []error
+	for _, target := range targets {
+		if err := process(target, opts); err != nil {
+			if !opts.KeepGoing {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+	return joinErrors(errs)
+}
+
+// WatchTargets runs watch on all targets at once until the process is
+// interrupted, then returns their combined errors
+func WatchTargets(targets []string, opts whitespace.Options, watch func(context.Context, string, whitespace.Options) error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Fprintf(os.Stderr, "Watching %s for changes; press Ctrl-C to stop\n", strings.Join(targets, ", "))
+
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = watch(ctx, target, opts)
+			if errs[i] != nil && !opts.KeepGoing {
+				// Without --keep-going one failed target stops the others
+				stop()
+			}
+		}()
+	}
+	wg.Wait()
+	return joinErrors(slices.DeleteFunc(errs, func(err error) bool { return err == nil }))
+}
+
+// CheckTargets runs check on each target in turn and collects the findings
+func CheckTargets(targets []string, opts whitespace.Options, check func(string, whitespace.Options) ([]whitespace.Finding, error)) ([]whitespace.Finding, error) {
+	var findings []whitespace.Finding
+	var errs []error
+	for _, target := range targets {
+		found, err := check(target, opts)
+		findings = append(findings, found...)
+		if err != nil {
+			if !opts.KeepGoing {
+				return findings, err
+			}
+			errs = append(errs, err)
+		}
+	}
+	return findings, joinErrors(errs)
+}
+
+// joinErrors combines per-target errors, merging --keep-going file errors
+// into one MultiError so they are reported together
+func joinErrors(errs []error) error {
+	if len(errs) <= 1 {
+		return errors.Join(errs...)
+	}
+	merged := &whitespace.MultiError{}
+	var other []error
+	for _, err := range errs {
+		var multi *whitespace.MultiError
+		if errors.As(err, &multi) {
+			merged.Errors = append(merged.Errors, multi.Errors...)
+		} else {
+			other = append(other, err)
+		}
+	}
+	if len(other) == 0 {
+		return merged
+	}
+	if len(merged.Errors) > 0 {
+		other = append(other, merged)
+	}
+	return errors.Join(other...)
+}
+
+// HandleError reports err on stderr and exits non-zero. Aggregated
+// --keep-going errors are listed one per line followed by a summary.
+func HandleError(err error) {
+	if err == nil {
+		return
+	}
+	var multi *whitespace.MultiError
+	if errors.As(err, &multi) {
+		for _, e := range multi.Errors {
+			fmt.Fprintln(os.Stderr, "Error:", e)
+		}
+		fmt.Fprintf(os.Stderr, "%d file(s) could not be processed\n", len(multi.Errors))
+	} else {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+	os.Exit(1)
+}
+
+// Finish closes the journal run started by Options, if any, and then reports
+// err like HandleError. With --fail-on-change it exits 1 if any file was
+// modified.
+func (cf *CommonFlags) Finish(opts whitespace.Options, err error) {
+	if j := opts.Journal; j != nil {
+		if closeErr := j.Close(); closeErr != nil {
+			err = errors.Join(err, closeErr)
+		} else if j.Count() > 0 {
+			cf.infof("Journal run %s recorded %d file(s); revert with: whitespace undo --journal %s %s\n", j.Run, j.Count(), j.Dir, j.Run)
+		}
 	}
-	if flag.NArg() > 1 {
-		fmt.Fprintf(os.Stderr, "Error: too many arguments\n")
-		flag.Usage()
+	cf.summary(cf.modified, "fixed")
+	HandleError(err)
+	if cf.FailOnChange && cf.modified > 0 {
+		cf.infof("%d file(s) modified\n", cf.modified)
 		os.Exit(1)
 	}
-	return target, nil
 }
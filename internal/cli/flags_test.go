@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/scottrigby/whitespace-tools/internal/whitespace"
+)
+
+// fileErrors returns a MultiError with an error for each path
+func fileErrors(paths ...string) *whitespace.MultiError {
+	m := &whitespace.MultiError{}
+	for _, path := range paths {
+		m.Errors = append(m.Errors, &whitespace.FileError{Path: path, Op: whitespace.OpProcess, Err: errors.New("failed")})
+	}
+	return m
+}
+
+// countErrors returns how many target errors err combines, other than
+// file errors, and how many file errors its MultiError holds
+func countErrors(err error) (targets, files int) {
+	var multi *whitespace.MultiError
+	if errors.As(err, &multi) {
+		files = len(multi.Errors)
+	}
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok && multi != err {
+		errs = joined.Unwrap()
+	}
+	for _, e := range errs {
+		if e != nil && !errors.As(e, &multi) {
+			targets++
+		}
+	}
+	return targets, files
+}
+
+func TestProcessTargets(t *testing.T) {
+	process := func(processed *[]string) func(string, whitespace.Options) error {
+		return func(target string, _ whitespace.Options) error {
+			*processed = append(*processed, target)
+			switch target {
+			case "bad":
+				return errors.New("failed")
+			case "bad-files":
+				return fileErrors(target+"/x", target+"/y")
+			}
+			return nil
+		}
+	}
+	tests := []struct {
+		name       string
+		targets    []string
+		keepGoing  bool
+		processed  string
+		targetErrs int
+		fileErrs   int
+	}{
+		{"all succeed", []string{"a", "b"}, false, "a b", 0, 0},
+		{"stops at the first failure", []string{"a", "bad", "b"}, false, "a bad", 1, 0},
+		{"keeps going", []string{"a", "bad", "b", "bad"}, true, "a bad b bad", 2, 0},
+		{"merges file errors", []string{"bad-files", "a", "bad-files"}, true, "bad-files a bad-files", 0, 4},
+		{"mixed errors", []string{"bad", "a", "bad-files"}, true, "bad a bad-files", 1, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var processed []string
+			err := ProcessTargets(tt.targets, whitespace.Options{KeepGoing: tt.keepGoing}, process(&processed))
+			if got := strings.Join(processed, " "); got != tt.processed {
+				t.Errorf("expected %q processed, got %q", tt.processed, got)
+			}
+			if targets, files := countErrors(err); targets != tt.targetErrs || files != tt.fileErrs {
+				t.Errorf("expected %d target and %d file errors, got %d and %d: %v", tt.targetErrs, tt.fileErrs, targets, files, err)
+			}
+		})
+	}
+}
+
+func TestJoinErrors(t *testing.T) {
+	if err := joinErrors(nil); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	single := errors.New("single")
+	if err := joinErrors([]error{single}); !errors.Is(err, single) || err.Error() != "single" {
+		t.Errorf("expected the single error, got %v", err)
+	}
+
+	// File errors from several targets are reported together
+	var merged *whitespace.MultiError
+	if err := joinErrors([]error{fileErrors("a"), fileErrors("b", "c")}); !errors.As(err, &merged) || len(merged.Errors) != 3 {
+		t.Errorf("expected one MultiError with 3 errors, got %v", err)
+	}
+	err := joinErrors([]error{fileErrors("a"), single, fileErrors("b")})
+	if !errors.Is(err, single) || !errors.As(err, &merged) || len(merged.Errors) != 2 {
+		t.Errorf("expected the target error and a merged MultiError, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package gitfilter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// CleanFunc returns the cleaned form of content, the blob for pathname
+// (relative to the top of the working tree) on its way into the index
+type CleanFunc func(pathname string, content []byte) ([]byte, error)
+
+// Serve speaks the filter protocol with git on r and w, answering each clean
+// request with clean. It returns nil when git closes the connection. A
+// request that clean fails is answered with an error status, which makes git
+// report it and, unless the filter is required, use the content unchanged;
+// errorLog, if set, receives a line describing the failure.
+func Serve(r io.Reader, w io.Writer, clean CleanFunc, errorLog io.Writer) error {
+	in := &pktReader{r: bufio.NewReader(r)}
+	out := &pktWriter{w: bufio.NewWriter(w)}
+
+	// Handshake: agree on version 2 and the clean capability
+	hello, err := in.readList()
+	if err != nil {
+		return fmt.Errorf("filter handshake: %w", err)
+	}
+	if len(hello) == 0 || hello[0] != "git-filter-client" || !slices.Contains(hello, "version=2") {
+		return fmt.Errorf("filter handshake: unexpected greeting %q", hello)
+	}
+	if err := out.writeList("git-filter-server", "version=2"); err != nil {
+		return err
+	}
+	capabilities, err := in.readList()
+	if err != nil {
+		return fmt.Errorf("filter handshake: %w", err)
+	}
+	if !slices.Contains(capabilities, "capability=clean") {
+		return fmt.Errorf("filter handshake: git does not offer the clean capability")
+	}
+	if err := out.writeList("capability=clean"); err != nil {
+		return err
+	}
+
+	for {
+		headers, err := in.readList()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		request := make(map[string]string, len(headers))
+		for _, h := range headers {
+			key, value, _ := strings.Cut(h, "=")
+			request[key] = value
+		}
+		content, err := in.readContent()
+		if err != nil {
+			return err
+		}
+
+		if command := request["command"]; command != "clean" {
+			if err := out.writeList("status=error"); err != nil {
+				return err
+			}
+			continue
+		}
+		cleaned, err := clean(request["pathname"], content)
+		if err != nil {
+			if errorLog != nil {
+				fmt.Fprintf(errorLog, "%s: %v\n", request["pathname"], err)
+			}
+			if err := out.writeList("status=error"); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := out.writeList("status=success"); err != nil {
+			return err
+		}
+		if err := out.writeContent(cleaned); err != nil {
+			return err
+		}
+		// An empty list keeps the "success" status
+		if err := out.flush(); err != nil {
+			return err
+		}
+	}
+}
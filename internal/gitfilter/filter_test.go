@@ -0,0 +1,127 @@
+package gitfilter
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// conversation records what git sends to the filter
+type conversation struct {
+	buf bytes.Buffer
+	w   *pktWriter
+}
+
+func newConversation() *conversation {
+	c := &conversation{}
+	c.w = &pktWriter{w: bufio.NewWriter(&c.buf)}
+	return c
+}
+
+func (c *conversation) handshake(t *testing.T) {
+	t.Helper()
+	if err := c.w.writeList("git-filter-client", "version=2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.w.writeList("capability=clean", "capability=smudge", "capability=delay"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (c *conversation) request(t *testing.T, command, pathname string, content []byte) {
+	t.Helper()
+	if err := c.w.writeList("command="+command, "pathname="+pathname); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.w.writeContent(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func upper(pathname string, content []byte) ([]byte, error) {
+	if strings.HasSuffix(pathname, ".bad") {
+		return nil, errors.New("cannot clean")
+	}
+	return bytes.ToUpper(content), nil
+}
+
+func TestServe(t *testing.T) {
+	c := newConversation()
+	c.handshake(t)
+	large := bytes.Repeat([]byte("abcdefgh"), maxPacketData/4) // spans several packets
+	c.request(t, "clean", "small.txt", []byte("hello\n"))
+	c.request(t, "clean", "large.txt", large)
+	c.request(t, "clean", "broken.bad", []byte("x"))
+	c.request(t, "smudge", "small.txt", []byte("y"))
+	c.request(t, "clean", "empty.txt", nil)
+
+	var out, errLog bytes.Buffer
+	if err := Serve(&c.buf, &out, upper, &errLog); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &pktReader{r: bufio.NewReader(&out)}
+	expectList := func(want ...string) {
+		t.Helper()
+		got, err := r.readList()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Fatalf("expected %q, got %q", want, got)
+		}
+	}
+	expectContent := func(want []byte) {
+		t.Helper()
+		got, err := r.readContent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("expected %d bytes %.20q, got %d bytes %.20q", len(want), want, len(got), got)
+		}
+	}
+
+	expectList("git-filter-server", "version=2")
+	expectList("capability=clean")
+
+	expectList("status=success")
+	expectContent([]byte("HELLO\n"))
+	expectList()
+
+	expectList("status=success")
+	expectContent(bytes.ToUpper(large))
+	expectList()
+
+	expectList("status=error")
+	expectList("status=error")
+
+	expectList("status=success")
+	expectContent(nil)
+	expectList()
+
+	if !strings.Contains(errLog.String(), "broken.bad: cannot clean") {
+		t.Errorf("expected the failure to be logged, got %q", errLog.String())
+	}
+}
+
+func TestServe_BadHandshake(t *testing.T) {
+	c := newConversation()
+	if err := c.w.writeList("git-filter-client", "version=1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Serve(&c.buf, &bytes.Buffer{}, upper, nil); err == nil {
+		t.Error("expected an error for an unsupported version")
+	}
+}
+
+func TestReadPacket_Invalid(t *testing.T) {
+	for _, input := range []string{"zzzz", "0002", "0010abc"} {
+		r := &pktReader{r: bufio.NewReader(strings.NewReader(input))}
+		if _, err := r.readPacket(); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+}
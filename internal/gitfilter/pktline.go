@@ -0,0 +1,120 @@
+// Package gitfilter implements the server side of git's long-running filter
+// process protocol (see gitattributes(5), "Long Running Filter Process"),
+// which git uses to stream blobs through a filter such as
+// filter.<driver>.process.
+package gitfilter
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxPacketData is the largest payload a single pkt-line may carry
+const maxPacketData = 65516
+
+// errFlush is returned by readPacket for a flush packet ("0000")
+var errFlush = errors.New("flush packet")
+
+// pktReader reads pkt-line framed data
+type pktReader struct {
+	r *bufio.Reader
+}
+
+// readPacket returns the payload of the next packet, or errFlush
+func (p *pktReader) readPacket() ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(p.r, header[:]); err != nil {
+		return nil, err
+	}
+	n, err := strconv.ParseUint(string(header[:]), 16, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pkt-line length %q", header)
+	}
+	switch {
+	case n == 0:
+		return nil, errFlush
+	case n < 4 || n-4 > maxPacketData:
+		return nil, fmt.Errorf("invalid pkt-line length %d", n)
+	}
+	data := make([]byte, n-4)
+	if _, err := io.ReadFull(p.r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// readList reads text packets up to a flush, without their trailing newlines
+func (p *pktReader) readList() ([]string, error) {
+	var list []string
+	for {
+		data, err := p.readPacket()
+		if errors.Is(err, errFlush) {
+			return list, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, strings.TrimSuffix(string(data), "\n"))
+	}
+}
+
+// readContent reads binary packets up to a flush
+func (p *pktReader) readContent() ([]byte, error) {
+	var content []byte
+	for {
+		data, err := p.readPacket()
+		if errors.Is(err, errFlush) {
+			return content, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		content = append(content, data...)
+	}
+}
+
+// pktWriter writes pkt-line framed data
+type pktWriter struct {
+	w *bufio.Writer
+}
+
+func (p *pktWriter) writePacket(data []byte) error {
+	if _, err := fmt.Fprintf(p.w, "%04x", len(data)+4); err != nil {
+		return err
+	}
+	_, err := p.w.Write(data)
+	return err
+}
+
+func (p *pktWriter) flush() error {
+	if _, err := p.w.WriteString("0000"); err != nil {
+		return err
+	}
+	return p.w.Flush()
+}
+
+// writeList writes each line as a text packet, followed by a flush
+func (p *pktWriter) writeList(lines ...string) error {
+	for _, line := range lines {
+		if err := p.writePacket([]byte(line + "\n")); err != nil {
+			return err
+		}
+	}
+	return p.flush()
+}
+
+// writeContent writes content split into packets, followed by a flush
+func (p *pktWriter) writeContent(content []byte) error {
+	for len(content) > 0 {
+		n := min(len(content), maxPacketData)
+		if err := p.writePacket(content[:n]); err != nil {
+			return err
+		}
+		content = content[n:]
+	}
+	return p.flush()
+}
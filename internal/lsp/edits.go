@@ -0,0 +1,53 @@
+package lsp
+
+import (
+	"sort"
+	"unicode/utf16"
+
+	"github.com/scottrigby/whitespace-tools/internal/whitespace"
+)
+
+// textEdits returns the edits that turn old into fixed
+func textEdits(old, fixed string) []textEdit {
+	return convertEdits(old, whitespace.Edits([]byte(old), []byte(fixed)))
+}
+
+// convertEdits converts byte offset edits of text to protocol edits
+func convertEdits(text string, edits []whitespace.Edit) []textEdit {
+	pos := newPositions(text)
+	converted := make([]textEdit, len(edits))
+	for i, e := range edits {
+		converted[i] = textEdit{Range: textRange{pos.at(e.Start), pos.at(e.End)}, NewText: e.Text}
+	}
+	return converted
+}
+
+// positions converts byte offsets in a text to protocol positions
+type positions struct {
+	text       string
+	lineStarts []int
+}
+
+func newPositions(text string) *positions {
+	p := &positions{text: text, lineStarts: []int{0}}
+	for i := range len(text) {
+		if text[i] == '\n' {
+			p.lineStarts = append(p.lineStarts, i+1)
+		}
+	}
+	return p
+}
+
+func (p *positions) at(offset int) position {
+	line := sort.Search(len(p.lineStarts), func(i int) bool { return p.lineStarts[i] > offset }) - 1
+	return position{Line: line, Character: utf16Len(p.text[p.lineStarts[line]:offset])}
+}
+
+// utf16Len counts the UTF-16 code units of s
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
@@ -0,0 +1,82 @@
+package lsp
+
+import (
+	"strings"
+	"testing"
+)
+
+// applyEdits applies edits, which must be sorted and not overlap, to text
+func applyEdits(t *testing.T, text string, edits []textEdit) string {
+	t.Helper()
+	offset := func(p position) int {
+		lines := strings.SplitAfter(text, "\n")
+		n := 0
+		for _, line := range lines[:p.Line] {
+			n += len(line)
+		}
+		units := 0
+		for i, r := range lines[p.Line] {
+			if units == p.Character {
+				return n + i
+			}
+			units += utf16Len(string(r))
+		}
+		if units != p.Character {
+			t.Fatalf("position %+v is past the end of its line", p)
+		}
+		return n + len(lines[p.Line])
+	}
+	var b strings.Builder
+	last := 0
+	for _, e := range edits {
+		start, end := offset(e.Range.Start), offset(e.Range.End)
+		if start < last {
+			t.Fatalf("edits overlap or are not sorted: %+v", edits)
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(e.NewText)
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+func TestTextEdits(t *testing.T) {
+	tests := []struct {
+		name  string
+		old   string
+		fixed string
+		edits int
+	}{
+		{"unchanged", "a\nb\n", "a\nb\n", 0},
+		{"trailing spaces", "a  \nb\nc\t\n", "a\nb\nc\n", 2},
+		{"missing newline", "a\nb", "a\nb\n", 1},
+		{"extra blank lines", "a\n\n\n", "a\n", 1},
+		{"trailing spaces and blank lines", "a \n  \n\n", "a\n", 2},
+		{"crlf", "a  \r\nb\r\n", "a\r\nb\r\n", 1},
+		{"crlf final newline", "a\r\n\r\n", "a\n", 1},
+		{"empty", "", "\n", 1},
+		{"markdown backslash", "break   \nnext\n", "break\\\nnext\n", 1},
+		{"non-ascii", "日本語 😀  \nx\n", "日本語 😀\nx\n", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edits := textEdits(tt.old, tt.fixed)
+			if len(edits) != tt.edits {
+				t.Errorf("expected %d edit(s), got %+v", tt.edits, edits)
+			}
+			if got := applyEdits(t, tt.old, edits); got != tt.fixed {
+				t.Errorf("applying %+v: expected %q, got %q", edits, tt.fixed, got)
+			}
+		})
+	}
+}
+
+func TestTextEdits_UTF16Positions(t *testing.T) {
+	// The emoji is two UTF-16 code units
+	edits := textEdits("😀a  \n", "😀a\n")
+	want := textRange{position{0, 3}, position{0, 5}}
+	if len(edits) != 1 || edits[0].Range != want {
+		t.Errorf("expected one edit at %+v, got %+v", want, edits)
+	}
+}
@@ -0,0 +1,132 @@
+// Package lsp implements a Language Server Protocol server that reports
+// whitespace problems as diagnostics and fixes them with code actions and
+// document formatting. It speaks JSON-RPC 2.0 over a stream, such as the
+// standard input and output of an editor's language client.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// JSON-RPC and LSP error codes
+const (
+	codeParseError           = -32700
+	codeInvalidRequest       = -32600
+	codeMethodNotFound       = -32601
+	codeInvalidParams        = -32602
+	codeInternalError        = -32603
+	codeServerNotInitialized = -32002
+)
+
+// message is a JSON-RPC request, notification or response. Requests and
+// responses have an ID; notifications do not.
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      json.RawMessage  `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  *json.RawMessage `json:"result,omitempty"` // a pointer so a null result is still sent
+	Error   *responseError   `json:"error,omitempty"`
+}
+
+// responseError is the error member of a failed response
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *responseError) Error() string {
+	return e.Message
+}
+
+// conn reads and writes messages framed by a Content-Length header
+type conn struct {
+	r *textproto.Reader
+	w *bufio.Writer
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{r: textproto.NewReader(bufio.NewReader(r)), w: bufio.NewWriter(w)}
+}
+
+// read returns the next message. It returns io.EOF when the stream ends
+// between messages, and a *responseError for a body that is not valid JSON.
+func (c *conn) read() (*message, error) {
+	header, err := c.r.ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF && len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("reading message header: %w", err)
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	if ct := header.Get("Content-Type"); ct != "" && !strings.Contains(ct, "utf-8") && !strings.Contains(ct, "utf8") {
+		return nil, fmt.Errorf("unsupported Content-Type %q", ct)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r.R, body); err != nil {
+		return nil, fmt.Errorf("reading message body: %w", err)
+	}
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, &responseError{Code: codeParseError, Message: err.Error()}
+	}
+	return &msg, nil
+}
+
+// write sends msg
+func (c *conn) write(msg *message) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	if _, err := c.w.Write(body); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
+
+// reply sends the response to the request with id: result, or err if set
+func (c *conn) reply(id json.RawMessage, result any, err error) error {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	msg := &message{ID: id}
+	if err != nil {
+		rerr, ok := err.(*responseError)
+		if !ok {
+			rerr = &responseError{Code: codeInternalError, Message: err.Error()}
+		}
+		msg.Error = rerr
+		return c.write(msg)
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	raw := json.RawMessage(data)
+	msg.Result = &raw
+	return c.write(msg)
+}
+
+// notify sends a notification
+func (c *conn) notify(method string, params any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.write(&message{Method: method, Params: data})
+}
@@ -0,0 +1,343 @@
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/scottrigby/whitespace-tools/internal/whitespace"
+)
+
+// server holds the state of one client connection
+type server struct {
+	conn        *conn
+	rules       []string
+	opts        whitespace.Options
+	errorLog    io.Writer
+	root        string               // workspace root directory, for exclude patterns
+	documents   map[string]*document // open documents by URI
+	initialized bool
+	shutdown    bool
+}
+
+// document is the editor's current text of an open file
+type document struct {
+	version int
+	text    string
+}
+
+// fix is a problem reported as a diagnostic with the edits that fix it
+type fix struct {
+	diagnostic diagnostic
+	edits      []textEdit
+}
+
+// Serve runs a language server on r and w until the client sends the exit
+// notification or closes the connection. Open documents are checked against
+// rules as they change, and fixed the same way the whitespace commands fix
+// files. errorLog, if set, receives a line for each document that could not
+// be checked.
+func Serve(r io.Reader, w io.Writer, rules []string, opts whitespace.Options, errorLog io.Writer) error {
+	s := &server{
+		conn:      newConn(r, w),
+		rules:     rules,
+		opts:      opts,
+		errorLog:  errorLog,
+		documents: make(map[string]*document),
+	}
+	for {
+		msg, err := s.conn.read()
+		if err == io.EOF {
+			return nil
+		}
+		var rerr *responseError
+		if errors.As(err, &rerr) {
+			// The message could not be parsed, so its ID is unknown
+			if err := s.conn.reply(nil, nil, rerr); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit before shutdown")
+			}
+			return nil
+		}
+		if len(msg.ID) == 0 {
+			if err := s.handleNotification(msg); err != nil {
+				return err
+			}
+			continue
+		}
+		result, err := s.handleRequest(msg)
+		if err := s.conn.reply(msg.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+// handleRequest returns the result of a request
+func (s *server) handleRequest(msg *message) (any, error) {
+	switch {
+	case s.shutdown:
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server is shutting down"}
+	case msg.Method == "initialize":
+		return s.initialize(msg.Params)
+	case !s.initialized:
+		return nil, &responseError{Code: codeServerNotInitialized, Message: "server not initialized"}
+	}
+
+	switch msg.Method {
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/codeAction":
+		var params codeActionParams
+		if err := unmarshalParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.codeActions(params), nil
+	case "textDocument/formatting":
+		var params formattingParams
+		if err := unmarshalParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.format(params.TextDocument.URI), nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "method not supported: " + msg.Method}
+}
+
+// handleNotification acts on a notification; unknown ones are ignored
+func (s *server) handleNotification(msg *message) error {
+	if !s.initialized {
+		return nil
+	}
+	switch msg.Method {
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := unmarshalParams(msg.Params, &params); err != nil {
+			return nil
+		}
+		doc := &document{version: params.TextDocument.Version, text: params.TextDocument.Text}
+		s.documents[params.TextDocument.URI] = doc
+		return s.publish(params.TextDocument.URI, doc)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := unmarshalParams(msg.Params, &params); err != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+		doc := &document{
+			version: params.TextDocument.Version,
+			text:    params.ContentChanges[len(params.ContentChanges)-1].Text,
+		}
+		s.documents[params.TextDocument.URI] = doc
+		return s.publish(params.TextDocument.URI, doc)
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := unmarshalParams(msg.Params, &params); err != nil {
+			return nil
+		}
+		delete(s.documents, params.TextDocument.URI)
+		// Clear the diagnostics of the closed document
+		return s.conn.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []diagnostic{},
+		})
+	}
+	return nil
+}
+
+func unmarshalParams(params json.RawMessage, v any) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *server) initialize(params json.RawMessage) (any, error) {
+	if s.initialized {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server already initialized"}
+	}
+	var p initializeParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.RootURI != "" {
+		s.root = uriPath(p.RootURI)
+	}
+	s.initialized = true
+	return map[string]any{
+		"capabilities": map[string]any{
+			"textDocumentSync": map[string]any{
+				"openClose": true,
+				"change":    1, // full document sync
+			},
+			"codeActionProvider": map[string]any{
+				"codeActionKinds": []string{kindQuickFix, kindSourceFixAll},
+			},
+			"documentFormattingProvider": true,
+		},
+		"serverInfo": map[string]any{"name": "whitespace"},
+	}, nil
+}
+
+// publish sends the diagnostics of doc
+func (s *server) publish(uri string, doc *document) error {
+	diagnostics := []diagnostic{}
+	for _, f := range s.fixes(uri, doc) {
+		diagnostics = append(diagnostics, f.diagnostic)
+	}
+	return s.conn.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         uri,
+		Version:     &doc.version,
+		Diagnostics: diagnostics,
+	})
+}
+
+// codeActions offers a quick fix for each problem on the requested lines,
+// and a single action fixing every problem in the document
+func (s *server) codeActions(params codeActionParams) []codeAction {
+	uri := params.TextDocument.URI
+	doc := s.documents[uri]
+	if doc == nil {
+		return []codeAction{}
+	}
+	wants := func(kind string) bool {
+		if len(params.Context.Only) == 0 {
+			return true
+		}
+		for _, only := range params.Context.Only {
+			if kind == only || strings.HasPrefix(kind, only+".") {
+				return true
+			}
+		}
+		return false
+	}
+
+	actions := []codeAction{}
+	fixes := s.fixes(uri, doc)
+	if wants(kindQuickFix) {
+		for _, f := range fixes {
+			if !f.diagnostic.Range.sharesLine(params.Range) {
+				continue
+			}
+			actions = append(actions, codeAction{
+				Title:       quickFixTitle(f.diagnostic.Code),
+				Kind:        kindQuickFix,
+				Diagnostics: []diagnostic{f.diagnostic},
+				IsPreferred: true,
+				Edit:        workspaceEdit{Changes: map[string][]textEdit{uri: f.edits}},
+			})
+		}
+	}
+	if len(fixes) > 0 && wants(kindSourceFixAll) {
+		actions = append(actions, codeAction{
+			Title: "Fix all whitespace problems",
+			Kind:  kindSourceFixAll,
+			Edit:  workspaceEdit{Changes: map[string][]textEdit{uri: s.format(uri)}},
+		})
+	}
+	return actions
+}
+
+func quickFixTitle(rule string) string {
+	switch rule {
+	case whitespace.RuleTrailingspace:
+		return "Remove trailing whitespace"
+	case whitespace.RuleNewline:
+		return "End file with a single newline"
+	}
+	return "Fix " + rule
+}
+
+// format returns the edits that fix every problem in the document
+func (s *server) format(uri string) []textEdit {
+	doc := s.documents[uri]
+	if doc == nil {
+		return []textEdit{}
+	}
+	path := s.documentPath(uri)
+	content := []byte(doc.text)
+	if ok, err := whitespace.SelectContent(path, content, s.opts); err != nil || !ok {
+		s.logError(path, err)
+		return []textEdit{}
+	}
+	fixed, err := whitespace.FixContent(path, content, s.rules, s.opts)
+	if err != nil {
+		s.logError(path, err)
+		return []textEdit{}
+	}
+	return textEdits(doc.text, string(fixed))
+}
+
+// fixes checks doc, pairing each problem with the edits that fix it
+func (s *server) fixes(uri string, doc *document) []fix {
+	path := s.documentPath(uri)
+	content := []byte(doc.text)
+	if ok, err := whitespace.SelectContent(path, content, s.opts); err != nil || !ok {
+		s.logError(path, err)
+		return nil
+	}
+	problems, err := whitespace.Problems(path, content, s.rules, s.opts)
+	if err != nil {
+		s.logError(path, err)
+		return nil
+	}
+	fixes := make([]fix, len(problems))
+	for i, p := range problems {
+		edits := convertEdits(doc.text, p.Edits)
+		fixes[i] = fix{
+			diagnostic: diagnostic{
+				Range:    textRange{edits[0].Range.Start, edits[len(edits)-1].Range.End},
+				Severity: severityWarning,
+				Code:     p.Rule,
+				Source:   "whitespace",
+				Message:  p.Message,
+			},
+			edits: edits,
+		}
+	}
+	return fixes
+}
+
+func (s *server) logError(path string, err error) {
+	if err != nil && s.errorLog != nil {
+		fmt.Fprintf(s.errorLog, "%s: %v\n", path, err)
+	}
+}
+
+// documentPath returns the path used to select and classify the document at
+// uri: relative to the workspace root when it is inside it
+func (s *server) documentPath(uri string) string {
+	path := uriPath(uri)
+	if s.root != "" {
+		if rel, err := filepath.Rel(s.root, path); err == nil && filepath.IsLocal(rel) {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return path
+}
+
+// uriPath returns the file path of a file URI, or the URI itself for other
+// schemes such as untitled documents
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	path := u.Path
+	// file:///C:/dir has the path /C:/dir
+	if runtime.GOOS == "windows" && len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.FromSlash(path)
+}
@@ -0,0 +1,203 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/scottrigby/whitespace-tools/internal/whitespace"
+)
+
+// client scripts the messages an editor sends
+type client struct {
+	buf  bytes.Buffer
+	conn *conn
+	id   int
+}
+
+func newClient() *client {
+	c := &client{}
+	c.conn = newConn(nil, &c.buf)
+	return c
+}
+
+func (c *client) request(t *testing.T, method string, params any) int {
+	t.Helper()
+	c.id++
+	data, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.conn.write(&message{ID: json.RawMessage(jsonString(t, c.id)), Method: method, Params: data}); err != nil {
+		t.Fatal(err)
+	}
+	return c.id
+}
+
+func (c *client) notify(t *testing.T, method string, params any) {
+	t.Helper()
+	if err := c.conn.notify(method, params); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func jsonString(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// serve runs the server over the script and returns what it sent back, as
+// messages and as raw output
+func serve(t *testing.T, c *client, rules []string) ([]*message, string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	err := Serve(&c.buf, &out, rules, whitespace.Options{}, io.Discard)
+	raw := out.String()
+	replies := newConn(&out, nil)
+	var msgs []*message
+	for {
+		msg, err := replies.read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, raw, err
+}
+
+func decode[T any](t *testing.T, data []byte) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatalf("decoding %s: %v", data, err)
+	}
+	return v
+}
+
+func open(uri, text string) map[string]any {
+	return map[string]any{"textDocument": map[string]any{"uri": uri, "languageId": "go", "version": 1, "text": text}}
+}
+
+func docID(uri string) map[string]any {
+	return map[string]any{"uri": uri}
+}
+
+func TestServe(t *testing.T) {
+	const (
+		uri      = "file:///work/main.go"
+		text     = "package main  \n\nfunc main() {}\t\n\n\n"
+		fixed    = "package main\n\nfunc main() {}\n"
+		excluded = "file:///work/vendor/lib/lib.go"
+	)
+	rules, err := whitespace.ParseRules("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := newClient()
+	c.request(t, "initialize", map[string]any{"processId": nil, "rootUri": "file:///work", "capabilities": map[string]any{}})
+	c.notify(t, "initialized", map[string]any{})
+	c.notify(t, "textDocument/didOpen", open(uri, text))
+	c.request(t, "textDocument/codeAction", map[string]any{
+		"textDocument": docID(uri),
+		"range":        textRange{position{0, 0}, position{0, 3}},
+		"context":      map[string]any{"diagnostics": []any{}},
+	})
+	c.request(t, "textDocument/formatting", map[string]any{"textDocument": docID(uri), "options": map[string]any{"tabSize": 4}})
+	c.request(t, "textDocument/hover", map[string]any{"textDocument": docID(uri), "position": position{0, 0}})
+	c.notify(t, "textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": uri, "version": 2},
+		"contentChanges": []any{map[string]any{"text": fixed}},
+	})
+	c.notify(t, "textDocument/didOpen", open(excluded, "package lib  \n"))
+	c.notify(t, "textDocument/didClose", map[string]any{"textDocument": docID(uri)})
+	c.request(t, "shutdown", nil)
+	c.notify(t, "exit", nil)
+
+	msgs, raw, err := serve(t, c, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 9 {
+		t.Fatalf("expected 9 messages, got %d", len(msgs))
+	}
+
+	// initialize
+	init := decode[map[string]map[string]any](t, *msgs[0].Result)
+	if init["capabilities"]["documentFormattingProvider"] != true {
+		t.Errorf("expected formatting to be advertised, got %v", init["capabilities"])
+	}
+
+	// didOpen
+	published := decode[publishDiagnosticsParams](t, msgs[1].Params)
+	if msgs[1].Method != "textDocument/publishDiagnostics" || published.URI != uri {
+		t.Fatalf("expected diagnostics for %s, got %s %s", uri, msgs[1].Method, msgs[1].Params)
+	}
+	want := []diagnostic{
+		{textRange{position{0, 12}, position{0, 14}}, severityWarning, "trailingspace", "whitespace", "trailing whitespace"},
+		{textRange{position{2, 14}, position{2, 15}}, severityWarning, "trailingspace", "whitespace", "trailing whitespace"},
+		{textRange{position{3, 0}, position{5, 0}}, severityWarning, "newline", "whitespace", "extra blank lines at end of file"},
+	}
+	if jsonString(t, published.Diagnostics) != jsonString(t, want) {
+		t.Errorf("expected diagnostics\n%s\ngot\n%s", jsonString(t, want), jsonString(t, published.Diagnostics))
+	}
+
+	// codeAction: the quick fix for the first line, and the fix-all action
+	actions := decode[[]codeAction](t, *msgs[2].Result)
+	if len(actions) != 2 || actions[0].Kind != kindQuickFix || actions[1].Kind != kindSourceFixAll {
+		t.Fatalf("expected a quick fix and a fix-all action, got %+v", actions)
+	}
+	if got := applyEdits(t, text, actions[0].Edit.Changes[uri]); got != "package main\n\nfunc main() {}\t\n\n\n" {
+		t.Errorf("quick fix produced %q", got)
+	}
+	if got := applyEdits(t, text, actions[1].Edit.Changes[uri]); got != fixed {
+		t.Errorf("fix-all action produced %q", got)
+	}
+
+	// formatting
+	if got := applyEdits(t, text, decode[[]textEdit](t, *msgs[3].Result)); got != fixed {
+		t.Errorf("formatting produced %q", got)
+	}
+
+	// hover is not supported
+	if msgs[4].Error == nil || msgs[4].Error.Code != codeMethodNotFound {
+		t.Errorf("expected method not found, got %+v", msgs[4])
+	}
+
+	// didChange to fixed text, didOpen of an excluded file and didClose all
+	// publish no diagnostics
+	for i, expectURI := range []string{uri, excluded, uri} {
+		published := decode[publishDiagnosticsParams](t, msgs[5+i].Params)
+		if published.URI != expectURI || len(published.Diagnostics) != 0 {
+			t.Errorf("expected no diagnostics for %s, got %s", expectURI, msgs[5+i].Params)
+		}
+	}
+
+	// shutdown, whose response must include the null result
+	if !strings.HasSuffix(raw, `{"jsonrpc":"2.0","id":5,"result":null}`) {
+		t.Errorf("expected a null shutdown result, got %+v", msgs[8])
+	}
+}
+
+func TestServe_Lifecycle(t *testing.T) {
+	c := newClient()
+	c.request(t, "textDocument/formatting", map[string]any{"textDocument": docID("file:///a.txt")})
+	c.notify(t, "exit", nil)
+
+	msgs, _, err := serve(t, c, []string{whitespace.RuleNewline})
+	if err == nil {
+		t.Error("expected an error for exit without shutdown")
+	}
+	if len(msgs) != 1 || msgs[0].Error == nil || msgs[0].Error.Code != codeServerNotInitialized {
+		t.Errorf("expected a not initialized error, got %+v", msgs)
+	}
+}
@@ -0,0 +1,161 @@
+package whitespace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// baselineVersion is the format version written to baseline files
+const baselineVersion = 1
+
+// Baseline records known findings so that only new ones are reported. A
+// finding is identified by its path, rule and a hash of the offending line
+// with surrounding whitespace trimmed, not by its line number, so baselined
+// lines may move or be re-indented without being reported.
+type Baseline struct {
+	counts map[baselineKey]int
+}
+
+type baselineKey struct {
+	path string
+	rule string
+	hash string
+}
+
+// baselineFile is the on-disk form of a Baseline
+type baselineFile struct {
+	Version  int             `json:"version"`
+	Findings []baselineEntry `json:"findings"`
+}
+
+type baselineEntry struct {
+	Path  string `json:"path"`
+	Rule  string `json:"rule"`
+	Hash  string `json:"hash"`
+	Count int    `json:"count,omitempty"` // Occurrences of identical lines, if more than one
+}
+
+// NewBaseline returns a baseline containing findings
+func NewBaseline(findings []Finding) *Baseline {
+	b := &Baseline{counts: make(map[baselineKey]int)}
+	for _, f := range findings {
+		b.counts[newBaselineKey(f)]++
+	}
+	return b
+}
+
+// ReadBaseline loads a baseline written by WriteFile
+func ReadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file baselineFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("baseline %s: %w", path, err)
+	}
+	if file.Version != baselineVersion {
+		return nil, fmt.Errorf("baseline %s: unsupported version %d", path, file.Version)
+	}
+	b := &Baseline{counts: make(map[baselineKey]int)}
+	for _, e := range file.Findings {
+		b.counts[baselineKey{e.Path, e.Rule, e.Hash}] += max(e.Count, 1)
+	}
+	return b, nil
+}
+
+// WriteFile saves the baseline to path, sorted so that it diffs well
+func (b *Baseline) WriteFile(path string) error {
+	file := baselineFile{Version: baselineVersion, Findings: []baselineEntry{}}
+	for k, n := range b.counts {
+		e := baselineEntry{Path: k.path, Rule: k.rule, Hash: k.hash}
+		if n > 1 {
+			e.Count = n
+		}
+		file.Findings = append(file.Findings, e)
+	}
+	sort.Slice(file.Findings, func(i, j int) bool {
+		a, c := file.Findings[i], file.Findings[j]
+		if a.Path != c.Path {
+			return a.Path < c.Path
+		}
+		if a.Rule != c.Rule {
+			return a.Rule < c.Rule
+		}
+		return a.Hash < c.Hash
+	})
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Replace drops the entries for rule and adds findings in their place, so
+// the tools for different rules can share one baseline file
+func (b *Baseline) Replace(rule string, findings []Finding) {
+	for k := range b.counts {
+		if k.rule == rule {
+			delete(b.counts, k)
+		}
+	}
+	for _, f := range findings {
+		b.counts[newBaselineKey(f)]++
+	}
+}
+
+// Len returns the number of findings in the baseline
+func (b *Baseline) Len() int {
+	n := 0
+	for _, c := range b.counts {
+		n += c
+	}
+	return n
+}
+
+// Filter returns the findings not covered by the baseline. Each baseline
+// entry covers as many identical findings as it was recorded with.
+func (b *Baseline) Filter(findings []Finding) []Finding {
+	remaining := make(map[baselineKey]int, len(b.counts))
+	for k, n := range b.counts {
+		remaining[k] = n
+	}
+	var out []Finding
+	for _, f := range findings {
+		k := newBaselineKey(f)
+		if remaining[k] > 0 {
+			remaining[k]--
+			continue
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+func newBaselineKey(f Finding) baselineKey {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(f.Text)))
+	return baselineKey{
+		path: baselinePath(f.Path),
+		rule: f.Rule,
+		hash: hex.EncodeToString(sum[:8]),
+	}
+}
+
+// baselinePath normalizes p to a slash-separated path relative to the
+// working directory, so a baseline applies whichever way a file is reached
+func baselinePath(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, abs); err == nil && filepath.IsLocal(rel) {
+				p = rel
+			}
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(p))
+}
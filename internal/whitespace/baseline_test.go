@@ -0,0 +1,82 @@
+package whitespace
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestBaseline_Filter(t *testing.T) {
+	known := checkTrailingspace([]byte("func a() { \n\treturn \n}\n"))
+	for i := range known {
+		known[i].Path = "a.go"
+	}
+	b := NewBaseline(known)
+
+	// The same lines moved down and re-indented, plus one new problem
+	edited := checkTrailingspace([]byte("// header\n\nfunc a() { \n    return  \n}\nvar x = 1 \n"))
+	for i := range edited {
+		edited[i].Path = "a.go"
+	}
+	got := b.Filter(edited)
+	if len(got) != 1 || got[0].Line != 6 {
+		t.Fatalf("expected only the new line 6, got %v", got)
+	}
+
+	// The same line in another file is new
+	other := append([]Finding(nil), known...)
+	for i := range other {
+		other[i].Path = "b.go"
+	}
+	if got := b.Filter(other); len(got) != len(other) {
+		t.Errorf("expected findings in another file to be new, got %v", got)
+	}
+}
+
+func TestBaseline_CountsDuplicates(t *testing.T) {
+	content := []byte("x \nx \n")
+	b := NewBaseline(checkTrailingspace(content))
+	if got := b.Filter(checkTrailingspace([]byte("x \nx \nx \n"))); len(got) != 1 {
+		t.Errorf("expected one extra duplicate to be new, got %v", got)
+	}
+}
+
+func TestBaseline_WriteRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	spaces := []Finding{{Path: "a.txt", Line: 1, Rule: RuleTrailingspace, Text: "a "}, {Path: "a.txt", Line: 2, Rule: RuleTrailingspace, Text: "a "}}
+	newlines := []Finding{{Path: "./a.txt", Line: 2, Rule: RuleNewline, Text: "a"}}
+
+	b := NewBaseline(spaces)
+	b.Replace(RuleNewline, newlines)
+	if err := b.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadBaseline(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Len() != 3 {
+		t.Fatalf("expected 3 findings, got %d", read.Len())
+	}
+	if got := read.Filter(append(spaces, newlines...)); len(got) != 0 {
+		t.Errorf("expected all findings to be baselined, got %v", got)
+	}
+
+	// Replacing one rule keeps the other
+	read.Replace(RuleTrailingspace, nil)
+	if read.Len() != 1 {
+		t.Errorf("expected only the newline finding to remain, got %d", read.Len())
+	}
+}
+
+func TestReadBaseline_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"garbage.json": "not json",
+		"future.json":  `{"version": 99, "findings": []}`,
+	})
+	for _, name := range []string{"garbage.json", "future.json", "missing.json"} {
+		if _, err := ReadBaseline(filepath.Join(dir, name)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package whitespace
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rule names identify the kind of problem a Finding reports
+const (
+	RuleTrailingspace = "trailingspace"
+	RuleNewline       = "newline"
+)
+
+// rules lists every rule name, in the order rules are applied
+var rules = []string{RuleTrailingspace, RuleNewline}
+
+// ParseRules validates a comma separated list of rule names; the empty
+// string selects all rules
+func ParseRules(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return rules, nil
+	}
+	var out []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if !slices.Contains(rules, name) {
+			return nil, fmt.Errorf("unknown rule %q (want %s)", name, strings.Join(rules, " or "))
+		}
+		out = append(out, name)
+	}
+	return out, nil
+}
+
+// Finding is a whitespace problem reported by a check instead of being fixed
+type Finding struct {
+	Path    string // Path of the file as reached from the target
+	Line    int    // 1-based line number
+	Column  int    // 1-based column, in characters, where the problem starts
+	Rule    string // RuleTrailingspace or RuleNewline
+	Message string // Human readable description
+	Text    string // Content of the offending line, without its line ending
+}
+
+// endColumn returns the 1-based column just past the text of line, where
+// whitespace problems are reported
+func endColumn(line string) int {
+	return utf8.RuneCountInString(line) + 1
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", f.Path, f.Line, f.Column, f.Rule, f.Message)
+}
+
+// checkFunc reports the problems in UTF-8 content that the matching fixFunc would fix
+type checkFunc func(text []byte) []Finding
+
+// checkFile runs check on the file at path without modifying it. Content in
+// other encodings is decoded first, so line numbers refer to characters.
+func checkFile(path string, check checkFunc, opts *Options) ([]Finding, error) {
+	input, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	text, err := decodeForCheck(input, opts.LegacyEncoding)
+	if err != nil {
+		return nil, err
+	}
+	findings := check(text)
+	for i := range findings {
+		findings[i].Path = path
+	}
+	return findings, nil
+}
+
+// decodeForCheck decodes data to UTF-8 for a checkFunc. A byte order mark
+// is dropped, as it is not part of the first line's text or columns.
+func decodeForCheck(data []byte, legacy Encoding) ([]byte, error) {
+	text, err := decodeLossless(data, sniffEncoding(data, legacy))
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimPrefix(text, []byte("\uFEFF")), nil
+}
+
+// checkTarget walks target like processTarget and collects the findings of
+// the check that checker returns for each file
+func checkTarget(target string, opts Options, checker func(path string) checkFunc) ([]Finding, error) {
+	var findings []Finding
+	err := processTarget(target, opts, func(path string) error {
+		found, err := checkFile(path, checker(path), &opts)
+		if err != nil {
+			return err
+		}
+		if len(found) > 0 {
+			logDecision(&opts, slog.LevelInfo, DecisionProblems, path, "problems", len(found))
+		} else {
+			logDecision(&opts, slog.LevelDebug, DecisionUnchanged, path)
+		}
+		findings = append(findings, found...)
+		return nil
+	})
+	return findings, err
+}
@@ -0,0 +1,171 @@
+package whitespace
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckTrailingspace(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		lines []int
+	}{
+		{"clean", "a\nb\n", nil},
+		{"spaces and tabs", "a \nb\n\tc\t\n", []int{1, 3}},
+		{"CRLF", "a \r\nb\r\n", []int{1}},
+		{"whitespace-only line", "a\n   \nb", []int{2}},
+		{"last line without newline", "a\nb  ", []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := checkTrailingspace([]byte(tt.input))
+			if len(findings) != len(tt.lines) {
+				t.Fatalf("expected lines %v, got %v", tt.lines, findings)
+			}
+			for i, f := range findings {
+				if f.Line != tt.lines[i] || f.Rule != RuleTrailingspace {
+					t.Errorf("expected line %d, got %v", tt.lines[i], f)
+				}
+			}
+			// A check reports exactly when the fix would change something
+			if changed := string(fixTrailingspace([]byte(tt.input))) != tt.input; changed != (len(findings) > 0) {
+				t.Errorf("check and fix disagree: fix changes content = %v", changed)
+			}
+		})
+	}
+}
+
+func TestCheckNewline(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		line    int
+		message string
+	}{
+		{"single newline", "a\nb\n", 0, ""},
+		{"missing", "a\nb", 2, "missing final newline"},
+		{"extra blank lines", "a\nb\n\n\n", 2, "extra blank lines at end of file"},
+		{"CRLF", "a\r\n", 1, "file does not end with exactly one newline"},
+		{"empty", "", 1, "missing final newline"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := checkNewline([]byte(tt.input))
+			if tt.line == 0 {
+				if len(findings) != 0 {
+					t.Fatalf("expected no findings, got %v", findings)
+				}
+			} else if len(findings) != 1 || findings[0].Line != tt.line || findings[0].Message != tt.message {
+				t.Fatalf("expected line %d %q, got %v", tt.line, tt.message, findings)
+			}
+			if changed := string(fixNewline([]byte(tt.input))) != tt.input; changed != (len(findings) > 0) {
+				t.Errorf("check and fix disagree: fix changes content = %v", changed)
+			}
+		})
+	}
+}
+
+func TestCheckWithOptions_DoesNotModify(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.txt":     "one \ntwo",
+		"sub/b.txt": "clean\n",
+	})
+	findings, err := CheckTrailingspaceWithOptions(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 1 || findings[0].Path != filepath.Join(dir, "a.txt") || findings[0].Text != "one " {
+		t.Errorf("unexpected trailingspace findings %v", findings)
+	}
+	findings, err = CheckNewlineWithOptions(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 1 || findings[0].Line != 2 {
+		t.Errorf("unexpected newline findings %v", findings)
+	}
+	if got := string(readFileBytes(t, filepath.Join(dir, "a.txt"))); got != "one \ntwo" {
+		t.Errorf("check modified the file: %q", got)
+	}
+}
+
+func TestFindingColumns(t *testing.T) {
+	trailing := checkTrailingspace([]byte("ok\nnaïve  \n\t\n"))
+	if len(trailing) != 2 || trailing[0].Column != 6 || trailing[1].Column != 1 {
+		t.Errorf("expected trailingspace columns 6 and 1, got %v", trailing)
+	}
+	newline := checkNewline([]byte("a\nlast"))
+	if len(newline) != 1 || newline[0].Column != 5 {
+		t.Errorf("expected newline column 5, got %v", newline)
+	}
+}
+
+func TestFindingColumns_BOM(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"utf8.txt":  "\uFEFFa  \nb",
+		"utf16.txt": string(encodeForTest(t, "\uFEFFa  \nb", EncodingUTF16LE)),
+	}
+	writeFiles(t, dir, files)
+	for name := range files {
+		trailing, err := CheckTrailingspaceWithOptions(filepath.Join(dir, name), Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(trailing) != 1 || trailing[0].Line != 1 || trailing[0].Column != 2 || trailing[0].Text != "a  " {
+			t.Errorf("%s: expected trailing whitespace at 1:2 in %q, got %v", name, "a  ", trailing)
+		}
+	}
+
+	// A BOM alone on the last line is not counted either
+	newline, err := CheckContent("a.txt", []byte("\uFEFFlast"), []string{RuleNewline}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(newline) != 1 || newline[0].Column != 5 || newline[0].Text != "last" {
+		t.Errorf("expected newline column 5, got %v", newline)
+	}
+}
+
+func TestCheckWithOptions_Checked(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "a \n", "b.txt": "b\n", "c.bin": "\x00"})
+	var checked []string
+	opts := Options{Checked: func(path string) { checked = append(checked, path) }}
+	if _, err := CheckTrailingspaceWithOptions(dir, opts); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a.txt", "b.txt"}
+	if got := relPaths(t, dir, checked); len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("expected %v checked, got %v", want, got)
+	}
+}
+
+func TestProcessWithOptions_CheckedAndSkipped(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.txt":         "a \n",
+		"b.txt":         "b\n",
+		"c.bin":         "\x00",
+		"d.log":         "d \n",
+		".hidden/e.txt": "e \n",
+	})
+	var checked, skipped []string
+	opts := Options{
+		ExcludePatterns: []string{"*.log"},
+		Checked:         func(path string) { checked = append(checked, path) },
+		Skipped:         func(path string) { skipped = append(skipped, path) },
+	}
+	if err := ProcessTrailingspaceWithOptions(dir, opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := relPaths(t, dir, checked); len(got) != 2 || got[0] != "a.txt" || got[1] != "b.txt" {
+		t.Errorf("expected a.txt and b.txt checked, got %v", got)
+	}
+	// Files in pruned directories are never reached
+	if got := relPaths(t, dir, skipped); len(got) != 2 || got[0] != "c.bin" || got[1] != "d.log" {
+		t.Errorf("expected c.bin and d.log skipped, got %v", got)
+	}
+}
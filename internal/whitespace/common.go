@@ -1,20 +1,43 @@
 package whitespace
 
 import (
+	"bytes"
 	"errors"
+	"io"
 	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
-
-	"github.com/gobwas/glob"
 )
 
 // Options for processing files
 type Options struct {
-	IncludeHidden   bool
-	ExcludePatterns []string    // Glob patterns to exclude
-	compiledGlobs   []glob.Glob // Compiled glob patterns (internal use)
+	IncludeHidden     bool
+	ExcludePatterns   []string          // Patterns to exclude (see match.go for the syntax)
+	NoDefaultExcludes bool              // Do not apply DefaultExcludes before ExcludePatterns
+	IncludeGenerated  bool              // Process files marked "Code generated ... DO NOT EDIT."
+	IncludePatterns   []string          // Patterns files must match (empty selects all files)
+	Types             []string          // File types files must match, e.g. "go", "yaml" (empty selects all files)
+	KeepGoing         bool              // Collect per-file errors and continue instead of aborting
+	Symlinks          SymlinkPolicy     // How symbolic links are treated (default: skip)
+	Hardlinks         HardlinkPolicy    // How files with several hard links are treated (default: preserve)
+	Warn              io.Writer         // Destination for warnings (nil discards them)
+	Backup            string            // Suffix for a copy of each modified file's original content ("" disables)
+	Journal           *Journal          // Records original content of modified files for undo (nil disables)
+	Modified          func(path string) // Called after each file is modified (nil disables)
+	Checked           func(path string) // Called after each file is checked or fixed without error (nil disables)
+	Skipped           func(path string) // Called for each file passed over by the selection rules (nil disables)
+	Log               *slog.Logger      // Records the decision made for each path (see log.go; nil disables)
+	TextDetect        TextDetect        // How text files are recognized (default: heuristic)
+	LegacyEncoding    Encoding          // Single-byte encoding assumed for text that is not valid UTF-8 ("" disables)
+	Force             bool              // Process explicitly named files even if they do not look like text
+	Markdown          MarkdownMode      // When Markdown hard line breaks are honored (default: auto, for .md files)
+	HardBreaks        HardBreakStyle    // How Markdown hard line breaks are written (default: preserve)
+	CodeBlocks        CodeBlockPolicy   // How fenced Markdown code blocks are treated (default: trim)
+	excludes          *patternSet       // Compiled exclude patterns (internal use)
+	includes          *patternSet       // Compiled include patterns (internal use)
+	fileTypes         []*FileType       // Resolved file types (internal use)
 }
 
 // isHidden returns true if the file/directory name starts with a dot
@@ -23,107 +46,423 @@ func isHidden(name string) bool {
 	return strings.HasPrefix(base, ".")
 }
 
-// compileExcludePatterns compiles glob patterns for efficient matching
+// compileExcludePatterns compiles exclude patterns for efficient matching
 func compileExcludePatterns(opts *Options) error {
-	if opts.compiledGlobs == nil && len(opts.ExcludePatterns) > 0 {
-		opts.compiledGlobs = make([]glob.Glob, 0, len(opts.ExcludePatterns))
-		for _, pattern := range opts.ExcludePatterns {
-			g, err := glob.Compile(pattern)
+	if patterns := excludePatterns(opts); opts.excludes == nil && len(patterns) > 0 {
+		ps, err := compilePatterns(patterns)
+		if err != nil {
+			return err
+		}
+		opts.excludes = ps
+	}
+	return nil
+}
+
+// compileIncludes compiles include patterns and resolves file types
+func compileIncludes(opts *Options) error {
+	if opts.includes == nil && len(opts.IncludePatterns) > 0 {
+		ps, err := compilePatterns(opts.IncludePatterns)
+		if err != nil {
+			return err
+		}
+		opts.includes = ps
+	}
+	if opts.fileTypes == nil && len(opts.Types) > 0 {
+		for _, name := range opts.Types {
+			ft, err := LookupFileType(name)
 			if err != nil {
 				return err
 			}
-			opts.compiledGlobs = append(opts.compiledGlobs, g)
+			opts.fileTypes = append(opts.fileTypes, ft)
 		}
 	}
 	return nil
 }
 
-// shouldExcludePath returns true if the path matches any exclude pattern
-func shouldExcludePath(path string, opts *Options) bool {
-	base := filepath.Base(path)
-	for _, g := range opts.compiledGlobs {
-		if g.Match(path) || g.Match(base) {
+// shouldIncludePath returns true if no include patterns or types are set, or
+// if the file at rel (relative to the target root) matches at least one of them
+func shouldIncludePath(rel string, opts *Options) bool {
+	if opts.includes == nil && len(opts.fileTypes) == 0 {
+		return true
+	}
+	if ok, _ := opts.includes.matchWithParents(rel, false); ok {
+		return true
+	}
+	for _, ft := range opts.fileTypes {
+		if ft.Matches(rel) {
 			return true
 		}
 	}
 	return false
 }
 
+// shouldExcludePath returns true if rel (relative to the target root) or one
+// of its parent directories matches the exclude patterns
+func shouldExcludePath(rel string, isDir bool, opts *Options) bool {
+	ok, _ := opts.excludes.matchWithParents(rel, isDir)
+	return ok
+}
+
 // ProcessFileFunc is a function type for processing individual files
 type ProcessFileFunc func(path string) error
 
+// fixFunc returns the fixed form of UTF-8 content. It must not modify input.
+type fixFunc func(input []byte) []byte
+
+// rewriteFile applies fix to the file at path and writes the result back in
+// place if it differs, after saving the original to any configured backup or
+// journal. Content in other encodings is decoded for fix and re-encoded, so
+// the file keeps its encoding. It reports whether the file was changed.
+func rewriteFile(path string, fix fixFunc, opts *Options) (bool, error) {
+	input, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	output, err := fixEncoded(input, sniffEncoding(input, opts.LegacyEncoding), fix)
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(input, output) {
+		logDecision(opts, slog.LevelDebug, DecisionUnchanged, path)
+		return false, nil
+	}
+
+	if opts.Backup != "" {
+		if err := writeBackup(path, opts.Backup, input); err != nil {
+			return false, err
+		}
+	}
+	if opts.Journal != nil {
+		if err := opts.Journal.Record(path, input, output); err != nil {
+			return false, err
+		}
+	}
+	if err := writeInPlace(path, output); err != nil {
+		return false, err
+	}
+	logDecision(opts, slog.LevelInfo, DecisionFixed, path)
+	if opts.Modified != nil {
+		opts.Modified(path)
+	}
+	return true, nil
+}
+
+// walker carries the state of a single processDir run
+type walker struct {
+	root        string // directory as given by the caller
+	opts        *Options
+	processFile ProcessFileFunc
+	errs        *errorCollector
+	rootReal    string          // resolved root, set when following symlinks
+	visited     map[string]bool // resolved directories already walked, for loop detection
+	explicit    bool            // processing a file named on the command line rather than walking
+}
+
 // processDir processes all files in a directory with the given options and file processor
 func processDir(dir string, opts Options, processFile ProcessFileFunc) error {
-	// Compile exclude patterns once
+	// Compile exclude and include patterns once
 	if err := compileExcludePatterns(&opts); err != nil {
 		return err
 	}
+	if err := compileIncludes(&opts); err != nil {
+		return err
+	}
 
-	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
+	w := &walker{
+		root:        dir,
+		opts:        &opts,
+		processFile: processFile,
+		errs:        &errorCollector{keepGoing: opts.KeepGoing},
+	}
+
+	// When following symlinks, walk resolved paths so loops can be detected,
+	// but keep reporting paths relative to the directory as given
+	realDir := dir
+	if opts.Symlinks.follows() {
+		var err error
+		if realDir, err = filepath.EvalSymlinks(dir); err != nil {
 			return err
 		}
+		w.rootReal = realDir
+		w.visited = make(map[string]bool)
+	}
 
-		// If this is a directory, check if we should skip it
-		if d.IsDir() {
-			// Don't skip the root directory
-			if path == dir {
-				return nil
+	if err := w.walk(realDir, dir); err != nil {
+		return err
+	}
+	return w.errs.err()
+}
+
+// walk visits realDir, presenting every path as if it were found under displayDir
+func (w *walker) walk(realDir, displayDir string) error {
+	return filepath.WalkDir(realDir, func(realPath string, d fs.DirEntry, err error) error {
+		path := realPath
+		if realDir != displayDir {
+			rel, relErr := filepath.Rel(realDir, realPath)
+			if relErr != nil {
+				return relErr
 			}
+			path = filepath.Join(displayDir, rel)
+		}
 
-			// Check exclude patterns
-			if shouldExcludePath(path, &opts) {
-				return filepath.SkipDir
+		if err != nil {
+			// The root itself could not be read; nothing to continue with
+			if path == w.root {
+				return err
 			}
+			return w.errs.add(path, OpWalk, err)
+		}
 
-			// Skip hidden directories based on options
-			if isHidden(d.Name()) {
-				// If IncludeHidden is true, never skip hidden dirs
-				if opts.IncludeHidden {
-					return nil
-				}
-				// If IncludeHidden is false (default), always skip hidden subdirectories
+		// If this is a directory, check if we should skip it
+		if d.IsDir() {
+			// Don't skip the root directory
+			if path != w.root && w.skipDir(path) {
 				return filepath.SkipDir
 			}
-
+			// Only directories actually walked count, so a link can still
+			// reach a pruned one
+			if w.visited != nil {
+				w.visited[realPath] = true
+			}
 			return nil
 		}
 
-		// Check exclude patterns for files
-		if shouldExcludePath(path, &opts) {
-			return nil
+		if d.Type()&fs.ModeSymlink != 0 {
+			return w.visitSymlink(path, realPath)
 		}
 
-		// Skip non-text files
-		isText, err := LooksText(path)
-		if err != nil {
-			return err
+		return w.visitFile(path, realPath, relativePath(w.root, path))
+	})
+}
+
+// skipDir reports whether a subdirectory should be pruned from the walk
+func (w *walker) skipDir(path string) bool {
+	decision, args := w.dirSkipReason(path)
+	if decision == "" {
+		return false
+	}
+	logDecision(w.opts, slog.LevelDebug, decision, path, args...)
+	return true
+}
+
+// dirSkipReason returns the decision that prunes a subdirectory from the
+// walk, with its log attributes, or "" if it is walked
+func (w *walker) dirSkipReason(path string) (string, []any) {
+	// Check exclude patterns
+	if ok, pattern := w.opts.excludes.matchWithParents(relativePath(w.root, path), true); ok {
+		return DecisionSkippedExcluded, []any{"pattern", pattern}
+	}
+
+	// Skip hidden directories based on options. If IncludeHidden is true,
+	// never skip hidden dirs; otherwise always skip hidden subdirectories.
+	if isHidden(path) && !w.opts.IncludeHidden {
+		return DecisionSkippedHidden, nil
+	}
+	return "", nil
+}
+
+// walkedInRoot reports whether the resolved directory target lies inside the
+// root and is reached by the walk itself, so a link to it is a second route
+// to the same files
+func (w *walker) walkedInRoot(target string) bool {
+	if w.rootReal == "" {
+		return false
+	}
+	rel, err := filepath.Rel(w.rootReal, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	if rel == "." {
+		return true
+	}
+	// target is resolved, so each directory on the way is a real one the walk
+	// enters unless it is pruned
+	path := w.root
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		path = filepath.Join(path, name)
+		if decision, _ := w.dirSkipReason(path); decision != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// skip reports a file that is not processed, and why
+func (w *walker) skip(path, decision string, args ...any) error {
+	skipFile(w.opts, path, decision, args...)
+	return nil
+}
+
+// skipFile reports a file that is not processed, and why
+func skipFile(opts *Options, path, decision string, args ...any) {
+	logDecision(opts, slog.LevelDebug, decision, path, args...)
+	if opts.Skipped != nil {
+		opts.Skipped(path)
+	}
+}
+
+// visitSymlink applies the symlink policy to a link found during the walk
+func (w *walker) visitSymlink(path, realPath string) error {
+	if !w.opts.Symlinks.follows() {
+		return w.skip(path, DecisionSkippedSymlink)
+	}
+
+	target, ok, err := resolveSymlink(realPath)
+	if err != nil {
+		return w.errs.add(path, OpWalk, err)
+	}
+	if !ok {
+		// Dangling link or link loop
+		return w.skip(path, DecisionSkippedSymlink)
+	}
+	if w.opts.Symlinks == SymlinksFollowWithinRoot && !isWithin(w.rootReal, target) {
+		return w.skip(path, DecisionSkippedSymlink)
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		return w.errs.add(path, OpWalk, err)
+	}
+	if info.IsDir() {
+		if w.skipDir(path) {
+			return nil
 		}
-		if !isText {
+		// A directory already walked, or that the walk reaches on its own,
+		// means a loop or a second route to the same files
+		if w.visited[target] || w.walkedInRoot(target) {
+			logDecision(w.opts, slog.LevelDebug, DecisionSkippedSymlink, path)
 			return nil
 		}
+		return w.walk(target, path)
+	}
+	return w.visitFile(path, target, relativePath(w.root, path))
+}
 
-		// Process the file
-		return processFile(path)
-	})
+// visitFile runs the exclude and text checks on a file and processes it.
+// realPath is used for inspection; the file is processed through path.
+// rel is the path used for pattern matching.
+func (w *walker) visitFile(path, realPath, rel string) error {
+	// Check exclude patterns, then include patterns and types, for files
+	if ok, pattern := w.opts.excludes.matchWithParents(rel, false); ok {
+		return w.skip(path, DecisionSkippedExcluded, "pattern", pattern)
+	}
+	// Never fix the backups of an earlier run with the same suffix
+	if w.opts.Backup != "" && strings.HasSuffix(path, w.opts.Backup) {
+		return w.skip(path, DecisionSkippedExcluded, "pattern", "*"+w.opts.Backup)
+	}
+	if !shouldIncludePath(rel, w.opts) {
+		return w.skip(path, DecisionSkippedNotIncluded)
+	}
+
+	// Skip FIFOs, sockets and devices before anything tries to open them,
+	// then apply the hard link policy
+	info, err := os.Stat(realPath)
+	if err != nil {
+		return w.errs.add(path, OpDetect, err)
+	}
+	if !info.Mode().IsRegular() {
+		return w.skip(path, DecisionSkippedSpecial)
+	}
+	if !allowHardlink(path, info, w.opts) {
+		return w.skip(path, DecisionSkippedHardlink)
+	}
+
+	// Skip non-text and generated files
+	in, err := inspect(realPath, w.opts.TextDetect, w.opts.LegacyEncoding)
+	if err != nil {
+		return w.errs.add(path, OpDetect, err)
+	}
+	if !in.text && !(w.explicit && w.opts.Force) {
+		return w.skip(path, DecisionSkippedBinary)
+	}
+	if in.generated && !w.opts.IncludeGenerated {
+		return w.skip(path, DecisionSkippedGenerated)
+	}
+
+	// Process the file
+	err = w.processFile(path)
+	if err == nil && w.opts.Checked != nil {
+		w.opts.Checked(path)
+	}
+	return w.errs.add(path, OpProcess, err)
+}
+
+// processSingleFile runs an explicitly named file through the same selection
+// as a walk: patterns are matched relative to the working directory, and text
+// detection applies unless opts.Force is set. Hidden directories are not
+// considered, since the file was named explicitly.
+func processSingleFile(target, real string, opts Options, processFile ProcessFileFunc) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(target)
+	if err != nil {
+		return err
+	}
+	w := &walker{
+		root:        wd,
+		opts:        &opts,
+		processFile: processFile,
+		errs:        &errorCollector{keepGoing: opts.KeepGoing},
+		explicit:    true,
+	}
+	if err := w.visitFile(target, real, relativePath(wd, abs)); err != nil {
+		return err
+	}
+	return w.errs.err()
 }
 
 // processTarget processes a file or directory target with the given options
 func processTarget(target string, opts Options, processFile ProcessFileFunc) error {
-	// Compile exclude patterns once
+	// Compile exclude and include patterns once
 	if err := compileExcludePatterns(&opts); err != nil {
 		return err
 	}
+	if err := compileIncludes(&opts); err != nil {
+		return err
+	}
 
-	info, err := os.Stat(target)
+	info, err := os.Lstat(target)
 	if err != nil {
 		return err
 	}
+	real := target
+	if info.Mode()&os.ModeSymlink != 0 {
+		if !opts.Symlinks.follows() {
+			skipFile(&opts, target, DecisionSkippedSymlink)
+			return nil
+		}
+		var ok bool
+		real, ok, err = resolveSymlink(target)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("dangling or looping symlink: " + target)
+		}
+		if opts.Symlinks == SymlinksFollowWithinRoot {
+			root, err := workdirRoot()
+			if err != nil {
+				return err
+			}
+			if !isWithin(root, real) {
+				skipFile(&opts, target, DecisionSkippedSymlink)
+				return nil
+			}
+		}
+		if info, err = os.Stat(real); err != nil {
+			return err
+		}
+	}
 	if info.Mode().IsRegular() {
-		return processFile(target)
+		return processSingleFile(target, real, opts, processFile)
 	}
 	if info.IsDir() {
 		return processDir(target, opts, processFile)
 	}
+	if isSpecial(info.Mode()) {
+		return errors.New("not a regular file (FIFO, socket or device): " + target)
+	}
 	return errors.New("not a file or directory: " + target)
 }
@@ -86,15 +86,35 @@ func TestFileSelection_SingleFile(t *testing.T) {
 		t.Errorf("expected %v, got %v", expected, mock.processedFiles)
 	}
 
-	// Test processing binary file (should be processed since we're targeting it directly)
+	// Test processing binary file (skipped like in a walk, unless forced)
 	mock.reset()
 	binaryFile := filepath.Join(tmpDir, "testdir", "binary")
 	if err := processTarget(binaryFile, Options{}, mock.process); err != nil {
 		t.Fatal(err)
 	}
 
+	if len(mock.processedFiles) != 0 {
+		t.Errorf("direct file processing should skip binary files, got %v", mock.processedFiles)
+	}
+
+	mock.reset()
+	if err := processTarget(binaryFile, Options{Force: true}, mock.process); err != nil {
+		t.Fatal(err)
+	}
+
 	if len(mock.processedFiles) != 1 || mock.processedFiles[0] != binaryFile {
-		t.Errorf("direct file processing should work even for binary files, got %v", mock.processedFiles)
+		t.Errorf("direct file processing should work for binary files with Force, got %v", mock.processedFiles)
+	}
+
+	// Test exclude patterns apply to files named directly
+	mock.reset()
+	excludedFile := filepath.Join(tmpDir, "testdir", "data.tmp")
+	if err := processTarget(excludedFile, Options{ExcludePatterns: []string{"*.tmp"}, Force: true}, mock.process); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mock.processedFiles) != 0 {
+		t.Errorf("direct file processing should honor exclude patterns, got %v", mock.processedFiles)
 	}
 }
 
@@ -0,0 +1,96 @@
+package whitespace
+
+import "fmt"
+
+// The functions in this file work on file content held in memory, such as a
+// blob staged in git, rather than on files found by walking a target.
+
+// SelectContent reports whether content, as the file at path (relative to
+// the working directory), would be processed under opts. It applies the same
+// pattern, text and generated-file checks as an explicitly named file.
+func SelectContent(path string, content []byte, opts Options) (bool, error) {
+	if err := compileExcludePatterns(&opts); err != nil {
+		return false, err
+	}
+	if err := compileIncludes(&opts); err != nil {
+		return false, err
+	}
+	rel := relativePath(".", path)
+	if shouldExcludePath(rel, false, &opts) || !shouldIncludePath(rel, &opts) {
+		return false, nil
+	}
+	sample := content[:min(len(content), sampleBytes)]
+	in := inspectSample(sample, path, opts.TextDetect, opts.LegacyEncoding, len(content) > sampleBytes)
+	if !in.text && !opts.Force {
+		return false, nil
+	}
+	return !in.generated || opts.IncludeGenerated, nil
+}
+
+// ruleFuncs returns the fix and check implementing rule for the file at path
+func ruleFuncs(rule, path string, opts *Options) (fixFunc, checkFunc, error) {
+	switch rule {
+	case RuleTrailingspace:
+		return trailingspaceFixer(path, opts), trailingspaceChecker(path, opts), nil
+	case RuleNewline:
+		return fixNewline, checkNewline, nil
+	}
+	return nil, nil, fmt.Errorf("unknown rule %q", rule)
+}
+
+// FixContent applies the fixes for rules to content as the file at path,
+// keeping its encoding
+func FixContent(path string, content []byte, rules []string, opts Options) ([]byte, error) {
+	enc := sniffEncoding(content, opts.LegacyEncoding)
+	for _, rule := range rules {
+		fix, _, err := ruleFuncs(rule, path, &opts)
+		if err != nil {
+			return nil, err
+		}
+		if content, err = fixEncoded(content, enc, fix); err != nil {
+			return nil, err
+		}
+	}
+	return content, nil
+}
+
+// FixFile applies the fixes for rules to the file at path like the tools
+// do: it is rewritten in place, keeping its mode, hard links and encoding,
+// after its original is saved to any configured backup or journal. It
+// reports whether the file was changed.
+func FixFile(path string, rules []string, opts Options) (bool, error) {
+	var fixes []fixFunc
+	for _, rule := range rules {
+		fix, _, err := ruleFuncs(rule, path, &opts)
+		if err != nil {
+			return false, err
+		}
+		fixes = append(fixes, fix)
+	}
+	return rewriteFile(path, func(text []byte) []byte {
+		for _, fix := range fixes {
+			text = fix(text)
+		}
+		return text
+	}, &opts)
+}
+
+// CheckContent reports the problems rules find in content as the file at path
+func CheckContent(path string, content []byte, rules []string, opts Options) ([]Finding, error) {
+	text, err := decodeForCheck(content, opts.LegacyEncoding)
+	if err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	for _, rule := range rules {
+		_, check, err := ruleFuncs(rule, path, &opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range check(text) {
+			f.Path = path
+			findings = append(findings, f)
+		}
+	}
+	return findings, nil
+}
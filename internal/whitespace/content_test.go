@@ -0,0 +1,108 @@
+package whitespace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSelectContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		content  string
+		opts     Options
+		selected bool
+	}{
+		{"text", "main.go", "package main\n", Options{}, true},
+		{"binary", "image.png", "\x89PNG\r\n\x1a\n\x00\x00", Options{}, false},
+		{"binary forced", "image.png", "\x89PNG\r\n\x1a\n\x00\x00", Options{Force: true}, true},
+		{"default exclude", "vendor/x/x.go", "package x\n", Options{}, false},
+		{"user exclude", "docs/a.md", "# A\n", Options{ExcludePatterns: []string{"docs/"}}, false},
+		{"type filter", "a.md", "# A\n", Options{Types: []string{"go"}}, false},
+		{"generated", "x.go", "// Code generated by tool. DO NOT EDIT.\n", Options{}, false},
+		{"generated included", "x.go", "// Code generated by tool. DO NOT EDIT.\n", Options{IncludeGenerated: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectContent(tt.path, []byte(tt.content), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.selected {
+				t.Errorf("expected %v, got %v", tt.selected, got)
+			}
+		})
+	}
+}
+
+func TestFixAndCheckContent(t *testing.T) {
+	content := []byte("hard break  \nnext \n\n\n")
+	all, err := ParseRules("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixed, err := FixContent("README.md", content, all, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(fixed) != "hard break  \nnext\n" {
+		t.Errorf("unexpected fix %q", fixed)
+	}
+	findings, err := CheckContent("README.md", content, all, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 2 || findings[0].Rule != RuleTrailingspace || findings[1].Rule != RuleNewline || findings[0].Path != "README.md" {
+		t.Errorf("unexpected findings %v", findings)
+	}
+
+	// UTF-16 content keeps its encoding
+	fixed, err = FixContent("notes.txt", utf16le("a \r\n"), []string{RuleTrailingspace}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(fixed) != string(utf16le("a\r\n")) {
+		t.Errorf("unexpected UTF-16 fix % x", fixed)
+	}
+}
+
+func TestFixFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "run.sh")
+	if err := os.WriteFile(path, []byte("echo hi  \n\n"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := FixFile(path, []string{RuleTrailingspace, RuleNewline}, Options{Backup: ".orig"})
+	if err != nil || !changed {
+		t.Fatalf("expected the file to change, got %v, %v", changed, err)
+	}
+	if got := readTrailingspaceFileContent(t, path); got != "echo hi\n" {
+		t.Errorf("expected both rules applied, got %q", got)
+	}
+	if got := readTrailingspaceFileContent(t, path+".orig"); got != "echo hi  \n\n" {
+		t.Errorf("expected a backup of the original, got %q", got)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o750 {
+		t.Errorf("expected mode 0750 to be kept, got %v, %v", info, err)
+	}
+	if changed, err := FixFile(path, []string{RuleTrailingspace, RuleNewline}, Options{}); err != nil || changed {
+		t.Errorf("expected a clean file to be left alone, got %v, %v", changed, err)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	if got, err := ParseRules("newline"); err != nil || len(got) != 1 || got[0] != RuleNewline {
+		t.Errorf("ParseRules(newline) = %v, %v", got, err)
+	}
+	if got, _ := ParseRules(" trailingspace , newline "); len(got) != 2 {
+		t.Errorf("expected two rules, got %v", got)
+	}
+	if _, err := ParseRules("tabs"); err == nil {
+		t.Error("expected error for unknown rule")
+	}
+}
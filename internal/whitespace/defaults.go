@@ -0,0 +1,67 @@
+package whitespace
+
+import "regexp"
+
+// DefaultExcludesVersion identifies the revision of DefaultExcludes. It is
+// bumped whenever the list changes so users can tell which set applied.
+const DefaultExcludesVersion = 2
+
+// DefaultExcludes are applied before any --exclude patterns unless
+// Options.NoDefaultExcludes is set. They cover vendored dependencies,
+// lockfiles, minified bundles, generated or golden test data and backups,
+// where rewriting whitespace either breaks checksums or churns files nobody
+// edits.
+// A later "!pattern" re-includes a file, except inside an excluded directory.
+var DefaultExcludes = []string{
+	// Vendored dependencies
+	"vendor/",
+	"node_modules/",
+	"bower_components/",
+	"third_party/",
+
+	// Lockfiles and checksums
+	"go.sum",
+	"go.work.sum",
+	"*.lock",
+	"package-lock.json",
+	"npm-shrinkwrap.json",
+	"pnpm-lock.yaml",
+
+	// Minified and bundled assets
+	"*.min.js",
+	"*.min.css",
+	"*.map",
+
+	// Generated code
+	"*.pb.go",
+	"*.pb.gw.go",
+	"*_pb2.py",
+	"zz_generated.*.go",
+
+	// Golden files and fixtures
+	"testdata/",
+	"*.golden",
+	"__snapshots__/",
+
+	// Editor and --backup copies
+	"*~",
+}
+
+// generatedMarker matches the standard "Code generated ... DO NOT EDIT." line
+// (https://go.dev/s/generatedcode), also when written with #, -- or /* comments
+var generatedMarker = regexp.MustCompile(`(?m)^\s*(//|#|--|/\*|<!--|;)\s*Code generated .* DO NOT EDIT\.`)
+
+// isGenerated reports whether sample contains a generated-code marker line
+func isGenerated(sample []byte) bool {
+	return generatedMarker.Match(sample)
+}
+
+// excludePatterns returns the effective exclude patterns for opts
+func excludePatterns(opts *Options) []string {
+	if opts.NoDefaultExcludes {
+		return opts.ExcludePatterns
+	}
+	patterns := make([]string, 0, len(DefaultExcludes)+len(opts.ExcludePatterns))
+	patterns = append(patterns, DefaultExcludes...)
+	return append(patterns, opts.ExcludePatterns...)
+}
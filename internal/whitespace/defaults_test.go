@@ -0,0 +1,117 @@
+package whitespace
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func createDefaultsStructure(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":                     "package main\n",
+		"go.sum":                      "example.com/x v1.0.0 h1:abc=\n",
+		"api/v1/api.pb.go":            "package v1\n",
+		"vendor/example.com/x/x.go":   "package x\n",
+		"web/node_modules/a/index.js": "module.exports = {}\n",
+		"web/app.min.js":              "var a=1;\n",
+		"web/app.js":                  "var a = 1;\n",
+		"yarn.lock":                   "# yarn lockfile v1\n",
+		"pkg/testdata/golden.txt":     "exact output   \n",
+		"pkg/gen.go":                  "// Code generated by stringer; DO NOT EDIT.\n\npackage pkg\n",
+		"scripts/gen.py":              "# Code generated by protoc. DO NOT EDIT.\n",
+		"pkg/notgen.go":               "package pkg\n\n// This is not Code generated by anything.\n",
+	})
+	return dir
+}
+
+func TestDefaultExcludes(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		expected []string
+	}{
+		{
+			name:     "defaults",
+			opts:     Options{},
+			expected: []string{"main.go", "pkg/notgen.go", "web/app.js"},
+		},
+		{
+			name:     "negation re-includes a default",
+			opts:     Options{ExcludePatterns: []string{"!go.sum"}},
+			expected: []string{"go.sum", "main.go", "pkg/notgen.go", "web/app.js"},
+		},
+		{
+			name:     "include generated",
+			opts:     Options{IncludeGenerated: true},
+			expected: []string{"main.go", "pkg/gen.go", "pkg/notgen.go", "scripts/gen.py", "web/app.js"},
+		},
+		{
+			name: "no default excludes",
+			opts: Options{NoDefaultExcludes: true},
+			expected: []string{
+				"api/v1/api.pb.go", "go.sum", "main.go", "pkg/notgen.go", "pkg/testdata/golden.txt",
+				"vendor/example.com/x/x.go", "web/app.js", "web/app.min.js", "web/node_modules/a/index.js", "yarn.lock",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := createDefaultsStructure(t)
+			mock := &mockProcessor{}
+			if err := processTarget(dir, tt.opts, mock.process); err != nil {
+				t.Fatal(err)
+			}
+			got := relPaths(t, dir, mock.processedFiles)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Fatalf("expected %v, got %v", tt.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultExcludes_SingleFile(t *testing.T) {
+	dir := createDefaultsStructure(t)
+	for _, name := range []string{"go.sum", "pkg/gen.go", "vendor/example.com/x/x.go"} {
+		mock := &mockProcessor{}
+		if err := processTarget(filepath.Join(dir, name), Options{}, mock.process); err != nil {
+			t.Fatal(err)
+		}
+		if len(mock.processedFiles) != 0 {
+			t.Errorf("%s: expected to be skipped by default, got %v", name, mock.processedFiles)
+		}
+	}
+}
+
+func TestDefaultExcludes_Compile(t *testing.T) {
+	if _, err := compilePatterns(DefaultExcludes); err != nil {
+		t.Fatalf("default excludes must compile: %v", err)
+	}
+}
+
+func TestIsGenerated(t *testing.T) {
+	tests := []struct {
+		content   string
+		generated bool
+	}{
+		{"// Code generated by protoc-gen-go. DO NOT EDIT.\n", true},
+		{"// Code generated DO NOT EDIT.\n", false}, // needs a generator description
+		{"// Copyright 2024\n\n// Code generated by mockgen. DO NOT EDIT.\npackage x\n", true},
+		{"# Code generated by make docs; DO NOT EDIT.\n", true},
+		{"/* Code generated by yacc. DO NOT EDIT. */\n", true},
+		{"<!-- Code generated by gomarkdoc. DO NOT EDIT. -->\n", true},
+		{"x := \"// Code generated by nothing. DO NOT EDIT.\"\n", false},
+		{"// code generated by lowercase. do not edit.\n", false},
+	}
+	for _, tt := range tests {
+		if got := isGenerated([]byte(tt.content)); got != tt.generated {
+			t.Errorf("%q: expected %v, got %v", tt.content, tt.generated, got)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package whitespace
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Unified diffs mark context lines with a leading space, so a blank context
+// line is a single space that looks like trailing whitespace. Removing it,
+// or any other whitespace inside a hunk, corrupts the patch. Only the lines
+// outside hunks, such as a commit message or headers, are trimmed. The
+// "-- " line that git format-patch puts before its mail signature, after
+// the last hunk, is kept as well.
+
+// diffExtensions are always treated as unified diffs
+var diffExtensions = map[string]bool{".patch": true, ".diff": true}
+
+var (
+	// diffHeader finds the file headers and first hunk of a unified diff in other files
+	diffHeader = regexp.MustCompile(`(?m)^--- .*\r?\n\+\+\+ .*\r?\n@@ -\d`)
+	// hunkHeader matches a hunk header and its old and new line counts
+	hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,(\d+))? \+\d+(?:,(\d+))? @@`)
+)
+
+// isDiffPath reports whether path has a patch or diff extension
+func isDiffPath(path string) bool {
+	return diffExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// sniffDiff protects diff hunks in text that looks like a unified diff
+func sniffDiff(text string) []bool {
+	if !strings.Contains(text, "\n@@ -") || !diffHeader.MatchString(text) {
+		return nil
+	}
+	return diffHunks(text)
+}
+
+// diffHunks marks every line inside a hunk, using the line counts in each
+// hunk header to find where it ends, and the signature separator after the
+// last hunk
+func diffHunks(text string) []bool {
+	lines := strings.Split(text, "\n")
+	protected := make([]bool, len(lines))
+	lastHunk := -1
+	for i := 0; i < len(lines); i++ {
+		m := hunkHeader.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		oldLines, newLines := hunkCount(m[1]), hunkCount(m[2])
+	hunk:
+		for (oldLines > 0 || newLines > 0) && i+1 < len(lines) {
+			line := strings.TrimSuffix(lines[i+1], "\r")
+			switch {
+			case line == "" || line[0] == ' ':
+				// Editors often strip blank context lines down to nothing
+				oldLines--
+				newLines--
+			case line[0] == '-':
+				oldLines--
+			case line[0] == '+':
+				newLines--
+			case line[0] == '\\':
+				// "\ No newline at end of file"
+			default:
+				break hunk
+			}
+			i++
+			protected[i] = true
+		}
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], `\`) {
+			i++
+			protected[i] = true
+		}
+		lastHunk = i
+	}
+	if lastHunk >= 0 {
+		for i := lastHunk + 1; i < len(lines); i++ {
+			if strings.TrimSuffix(lines[i], "\r") == "-- " {
+				protected[i] = true
+				break
+			}
+		}
+	}
+	return protected
+}
+
+// hunkCount parses a hunk header line count, which defaults to 1 when omitted
+func hunkCount(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
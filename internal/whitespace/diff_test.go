@@ -0,0 +1,114 @@
+package whitespace
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testPatch is a git format-patch mail with trailing whitespace in its
+// headers, in blank context lines and in changed lines
+const testPatch = "From 1234567 Mon Sep 17 00:00:00 2001 \n" +
+	"Subject: [PATCH] Update greeting  \n" +
+	"\n" +
+	"Explain the change. \n" +
+	"---\n" +
+	" hello.txt | 2 +-\n" +
+	"\n" +
+	"diff --git a/hello.txt b/hello.txt\n" +
+	"--- a/hello.txt\n" +
+	"+++ b/hello.txt\n" +
+	"@@ -1,5 +1,5 @@ \n" +
+	" line one\n" +
+	" \n" +
+	"-hello \n" +
+	"+hello world  \n" +
+	" \n" +
+	" line five\n" +
+	"-- \n" +
+	"2.40.0\n"
+
+const testPatchFixed = "From 1234567 Mon Sep 17 00:00:00 2001\n" +
+	"Subject: [PATCH] Update greeting\n" +
+	"\n" +
+	"Explain the change.\n" +
+	"---\n" +
+	" hello.txt | 2 +-\n" +
+	"\n" +
+	"diff --git a/hello.txt b/hello.txt\n" +
+	"--- a/hello.txt\n" +
+	"+++ b/hello.txt\n" +
+	"@@ -1,5 +1,5 @@\n" +
+	" line one\n" +
+	" \n" +
+	"-hello \n" +
+	"+hello world  \n" +
+	" \n" +
+	" line five\n" +
+	"-- \n" +
+	"2.40.0\n"
+
+// gitApplyCheck reports whether git accepts patch for the tree in dir
+func gitApplyCheck(t *testing.T, dir, patch string) bool {
+	t.Helper()
+	path := filepath.Join(dir, "change.patch")
+	if err := os.WriteFile(path, []byte(patch), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("git", "apply", "--check", path)
+	cmd.Dir = dir
+	return cmd.Run() == nil
+}
+
+func TestDiff_GitApplyAfterFix(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"hello.txt": "line one\n\nhello \n\nline five\n"})
+
+	if !gitApplyCheck(t, dir, testPatch) {
+		t.Fatal("fixture patch does not apply")
+	}
+	fixed := string(trailingspaceFixer("change.patch", &Options{})([]byte(testPatch)))
+	if fixed != testPatchFixed {
+		t.Errorf("expected %q, got %q", testPatchFixed, fixed)
+	}
+	if !gitApplyCheck(t, dir, fixed) {
+		t.Error("patch no longer applies after fixing")
+	}
+	// Plain trimming would have corrupted it
+	if gitApplyCheck(t, dir, string(fixTrailingspace([]byte(testPatch)))) {
+		t.Error("expected plain trimming to break the fixture")
+	}
+}
+
+func TestDiff_Detection(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		input    string
+		expected string
+	}{
+		{"patch extension", "fix.diff", "@@ -1 +1 @@\n-a \n+b \n", "@@ -1 +1 @@\n-a \n+b \n"},
+		{"sniffed in other files", "notes.txt", "--- a/x\n+++ b/x\n@@ -1 +1 @@\n-a \n+b \ntrailer \n", "--- a/x\n+++ b/x\n@@ -1 +1 @@\n-a \n+b \ntrailer\n"},
+		{"no newline marker", "x.patch", "@@ -1 +1 @@\n-a\n+a \n\\ No newline at end of file\nafter \n", "@@ -1 +1 @@\n-a\n+a \n\\ No newline at end of file\nafter\n"},
+		{"stripped blank context", "x.patch", "@@ -1,3 +1,3 @@\n x\n\n-y \n+z\nafter \n", "@@ -1,3 +1,3 @@\n x\n\n-y \n+z\nafter\n"},
+		{"signature separator", "x.patch", "@@ -1 +1 @@\n-a \n+b \n-- \n2.40.0 \n", "@@ -1 +1 @@\n-a \n+b \n-- \n2.40.0\n"},
+		{"separator before a hunk", "x.patch", "-- \n@@ -1 +1 @@\n-a \n+b \n", "--\n@@ -1 +1 @@\n-a \n+b \n"},
+		{"not a diff", "notes.txt", "see @@ -1 +1 @@ \n-a \n", "see @@ -1 +1 @@\n-a\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(trailingspaceFixer(tt.path, &Options{})([]byte(tt.input))); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+			findings := trailingspaceChecker(tt.path, &Options{})([]byte(tt.input))
+			if want := strings.Count(tt.input, " \n") - strings.Count(tt.expected, " \n"); len(findings) != want {
+				t.Errorf("expected %d findings, got %v", want, findings)
+			}
+		})
+	}
+}
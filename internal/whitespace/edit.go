@@ -0,0 +1,143 @@
+package whitespace
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Edit replaces the bytes of content from Start to End with Text
+type Edit struct {
+	Start, End int
+	Text       string
+}
+
+// Problem is a Finding with the edits that fix it alone
+type Problem struct {
+	Finding
+	Edits []Edit
+}
+
+// Problems reports the problems rules find in content as the file at path,
+// like CheckContent, each with the part of FixContent's result that fixes
+// it. Editors and linters use the edits to offer a fix per problem.
+func Problems(path string, content []byte, rules []string, opts Options) ([]Problem, error) {
+	lineStarts := []int{0}
+	for i, b := range content {
+		if b == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	lineOf := func(offset int) int {
+		return sort.SearchInts(lineStarts, offset+1)
+	}
+
+	var problems []Problem
+	for _, rule := range rules {
+		findings, err := CheckContent(path, content, []string{rule}, opts)
+		if err != nil || len(findings) == 0 {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		fixed, err := FixContent(path, content, []string{rule}, opts)
+		if err != nil {
+			return nil, err
+		}
+		edits := Edits(content, fixed)
+		for _, f := range findings {
+			p := Problem{Finding: f}
+			for _, e := range edits {
+				// The newline fix edits the end of the file, which may start
+				// on a line after the one reported
+				if rule == RuleNewline || lineOf(e.Start) == f.Line {
+					p.Edits = append(p.Edits, e)
+				}
+			}
+			if len(p.Edits) > 0 {
+				problems = append(problems, p)
+			}
+		}
+	}
+	return problems, nil
+}
+
+// Edits returns the sorted, non-overlapping edits that turn content into
+// fixed. The fixes only change the ends of lines and the end of the
+// content, so lines are compared pairwise and each changed line becomes an
+// edit of just the part that changed. A CRLF line ending is never split
+// between an edit and the text around it, since editors treat it as one
+// character.
+func Edits(content, fixed []byte) []Edit {
+	if bytes.Equal(content, fixed) {
+		return nil
+	}
+	oldLines, fixedLines := bytes.Split(content, []byte("\n")), bytes.Split(fixed, []byte("\n"))
+	n := min(len(oldLines), len(fixedLines))
+
+	var edits []Edit
+	oldStart, fixedStart := 0, 0
+	for i := range n {
+		o, f := oldLines[i], fixedLines[i]
+		if !bytes.Equal(o, f) {
+			prefix := commonPrefix(o, f)
+			suffix := commonSuffix(o[prefix:], f[prefix:])
+			edits = append(edits, Edit{oldStart + prefix, oldStart + len(o) - suffix, string(f[prefix : len(f)-suffix])})
+		}
+		if i < n-1 {
+			oldStart += len(o) + 1
+			fixedStart += len(f) + 1
+		}
+	}
+	// Lines added or removed at the end
+	if len(oldLines) != len(fixedLines) {
+		oldEnd := oldStart + len(oldLines[n-1])
+		fixedEnd := fixedStart + len(fixedLines[n-1])
+		edits = append(edits, Edit{oldEnd, len(content), string(fixed[fixedEnd:])})
+	}
+
+	edits = mergeEdits(edits)
+	for i := range edits {
+		e := &edits[i]
+		if e.Start > 0 && e.Start < len(content) && content[e.Start-1] == '\r' && content[e.Start] == '\n' {
+			e.Start--
+			e.Text = "\r" + e.Text
+		}
+		if e.End > 0 && e.End < len(content) && content[e.End-1] == '\r' && content[e.End] == '\n' {
+			e.End++
+			e.Text += "\n"
+		}
+	}
+	return mergeEdits(edits)
+}
+
+// mergeEdits joins sorted edits that touch; the fixes never produce
+// overlapping ones
+func mergeEdits(edits []Edit) []Edit {
+	var merged []Edit
+	for _, e := range edits {
+		if last := len(merged) - 1; last >= 0 && e.Start <= merged[last].End {
+			merged[last].End = max(merged[last].End, e.End)
+			merged[last].Text += e.Text
+			continue
+		}
+		merged = append(merged, e)
+	}
+	return merged
+}
+
+func commonPrefix(a, b []byte) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+func commonSuffix(a, b []byte) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[len(a)-1-i] == b[len(b)-1-i] {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,89 @@
+package whitespace
+
+import (
+	"strings"
+	"testing"
+)
+
+// applyEdits applies sorted, non-overlapping edits to content
+func applyEdits(t *testing.T, content string, edits []Edit) string {
+	t.Helper()
+	var b strings.Builder
+	last := 0
+	for _, e := range edits {
+		if e.Start < last || e.End < e.Start || e.End > len(content) {
+			t.Fatalf("invalid edits %+v for %d bytes", edits, len(content))
+		}
+		b.WriteString(content[last:e.Start])
+		b.WriteString(e.Text)
+		last = e.End
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
+
+func TestEdits(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		fixed   string
+		edits   []Edit
+	}{
+		{"unchanged", "a\n", "a\n", nil},
+		{"trailing spaces", "a  \nb\nc\t\n", "a\nb\nc\n", []Edit{{1, 3, ""}, {7, 8, ""}}},
+		{"missing newline", "a", "a\n", []Edit{{1, 1, "\n"}}},
+		{"extra blank lines", "a\n\n\n", "a\n", []Edit{{2, 4, ""}}},
+		{"crlf trailing spaces", "a  \r\nb\r\n", "a\r\nb\r\n", []Edit{{1, 3, ""}}},
+		{"crlf final newline", "a\r\n\r\n", "a\n", []Edit{{1, 5, "\n"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edits := Edits([]byte(tt.content), []byte(tt.fixed))
+			if len(edits) != len(tt.edits) {
+				t.Fatalf("expected %+v, got %+v", tt.edits, edits)
+			}
+			for i := range edits {
+				if edits[i] != tt.edits[i] {
+					t.Errorf("expected %+v, got %+v", tt.edits, edits)
+				}
+			}
+			if got := applyEdits(t, tt.content, edits); got != tt.fixed {
+				t.Errorf("applying the edits gave %q", got)
+			}
+		})
+	}
+}
+
+func TestProblems(t *testing.T) {
+	content := "x := `raw  \nstring`  \ny := 1\t\n\n\n"
+	rules, err := ParseRules("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems, err := Problems("main.go", []byte(content), rules, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		line  int
+		rule  string
+		fixed string
+	}{
+		{2, RuleTrailingspace, "x := `raw  \nstring`\ny := 1\t\n\n\n"},
+		{3, RuleTrailingspace, "x := `raw  \nstring`  \ny := 1\n\n\n"},
+		{3, RuleNewline, "x := `raw  \nstring`  \ny := 1\t\n"},
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("expected %d problems, got %+v", len(want), problems)
+	}
+	for i, w := range want {
+		p := problems[i]
+		if p.Line != w.line || p.Rule != w.rule {
+			t.Errorf("problem %d: expected %s on line %d, got %s on line %d", i, w.rule, w.line, p.Rule, p.Line)
+		}
+		// Each problem's edits fix it alone
+		if got := applyEdits(t, content, p.Edits); got != w.fixed {
+			t.Errorf("problem %d: fixing it gave %q, expected %q", i, got, w.fixed)
+		}
+	}
+}
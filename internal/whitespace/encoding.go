@@ -0,0 +1,212 @@
+package whitespace
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Single-byte encodings that can be configured for text that is not valid UTF-8
+const (
+	EncodingLatin1      Encoding = "iso-8859-1"
+	EncodingWindows1252 Encoding = "windows-1252"
+)
+
+// ParseLegacyEncoding validates a --legacy-encoding value; the empty string disables it
+func ParseLegacyEncoding(s string) (Encoding, error) {
+	switch strings.ToLower(s) {
+	case "":
+		return "", nil
+	case "latin1", "latin-1", "iso-8859-1", "iso8859-1":
+		return EncodingLatin1, nil
+	case "windows-1252", "cp1252":
+		return EncodingWindows1252, nil
+	}
+	return "", fmt.Errorf("invalid legacy encoding %q (want latin1 or windows-1252)", s)
+}
+
+// sniffUTF16 recognizes BOM-less UTF-16 by its NUL pattern: mostly-ASCII text
+// has a zero high byte in nearly every code unit and almost no other NULs.
+func sniffUTF16(sample []byte) (Encoding, bool) {
+	units := len(sample) / 2
+	if units < 2 {
+		return "", false
+	}
+	var evenNUL, oddNUL int
+	for i := 0; i+1 < len(sample); i += 2 {
+		if sample[i] == 0 {
+			evenNUL++
+		}
+		if sample[i+1] == 0 {
+			oddNUL++
+		}
+	}
+	switch {
+	case oddNUL*10 >= units*7 && evenNUL*10 <= units:
+		return EncodingUTF16LE, true
+	case evenNUL*10 >= units*7 && oddNUL*10 <= units:
+		return EncodingUTF16BE, true
+	}
+	return "", false
+}
+
+// sniffEncoding determines the encoding of a whole text file's content
+func sniffEncoding(data []byte, legacy Encoding) Encoding {
+	if enc, ok := bomEncoding(data); ok {
+		return enc
+	}
+	if enc, ok := sniffUTF16(data); ok {
+		return enc
+	}
+	if legacy != "" && !utf8.Valid(data) {
+		return legacy
+	}
+	return EncodingUTF8
+}
+
+// windows1252 maps bytes 0x80-0x9F to runes; undefined bytes map to the
+// matching C1 control so that every byte round-trips.
+var windows1252 = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+// decodeText converts data in enc to UTF-8
+func decodeText(data []byte, enc Encoding) ([]byte, error) {
+	switch enc {
+	case EncodingUTF8:
+		return data, nil
+	case EncodingUTF16LE, EncodingUTF16BE:
+		if len(data)%2 != 0 {
+			return nil, fmt.Errorf("odd number of bytes for %s", enc)
+		}
+		order := byteOrder(enc)
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = order.Uint16(data[2*i:])
+		}
+		return []byte(string(utf16.Decode(units))), nil
+	case EncodingUTF32LE, EncodingUTF32BE:
+		if len(data)%4 != 0 {
+			return nil, fmt.Errorf("byte count not a multiple of 4 for %s", enc)
+		}
+		order := byteOrder(enc)
+		out := make([]byte, 0, len(data))
+		for i := 0; i < len(data); i += 4 {
+			out = utf8.AppendRune(out, rune(order.Uint32(data[i:])))
+		}
+		return out, nil
+	case EncodingLatin1, EncodingWindows1252:
+		out := make([]byte, 0, len(data))
+		for _, b := range data {
+			r := rune(b)
+			if enc == EncodingWindows1252 && b >= 0x80 && b < 0xA0 {
+				r = windows1252[b-0x80]
+			}
+			out = utf8.AppendRune(out, r)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported encoding %s", enc)
+}
+
+// encodeText converts UTF-8 text back to enc
+func encodeText(text []byte, enc Encoding) ([]byte, error) {
+	switch enc {
+	case EncodingUTF8:
+		return text, nil
+	case EncodingUTF16LE, EncodingUTF16BE:
+		order := byteOrder(enc)
+		units := utf16.Encode([]rune(string(text)))
+		out := make([]byte, 2*len(units))
+		for i, u := range units {
+			order.PutUint16(out[2*i:], u)
+		}
+		return out, nil
+	case EncodingUTF32LE, EncodingUTF32BE:
+		order := byteOrder(enc)
+		runes := []rune(string(text))
+		out := make([]byte, 4*len(runes))
+		for i, r := range runes {
+			order.PutUint32(out[4*i:], uint32(r))
+		}
+		return out, nil
+	case EncodingLatin1, EncodingWindows1252:
+		out := make([]byte, 0, len(text))
+		for _, r := range string(text) {
+			b, ok := singleByte(r, enc)
+			if !ok {
+				return nil, fmt.Errorf("%U cannot be represented in %s", r, enc)
+			}
+			out = append(out, b)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported encoding %s", enc)
+}
+
+// singleByte returns the byte for r in a single-byte encoding
+func singleByte(r rune, enc Encoding) (byte, bool) {
+	if enc == EncodingWindows1252 {
+		if r >= 0x80 && r < 0xA0 {
+			// Only the undefined positions keep their C1 control
+			b := byte(r)
+			return b, windows1252[b-0x80] == r
+		}
+		for i, c := range windows1252 {
+			if c == r {
+				return byte(0x80 + i), true
+			}
+		}
+	}
+	if r > 0xFF {
+		return 0, false
+	}
+	return byte(r), true
+}
+
+// byteOrder returns the byte order of a UTF-16/32 encoding
+func byteOrder(enc Encoding) binary.ByteOrder {
+	if enc == EncodingUTF16BE || enc == EncodingUTF32BE {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// errNotRoundTrip is returned for content that would not survive decoding and re-encoding
+var errNotRoundTrip = errors.New("content does not round-trip through its encoding")
+
+// decodeLossless converts data in enc to UTF-8, rejecting content that
+// cannot be decoded losslessly (for example UTF-16 with unpaired surrogates)
+func decodeLossless(data []byte, enc Encoding) ([]byte, error) {
+	if enc == EncodingUTF8 {
+		return data, nil
+	}
+	text, err := decodeText(data, enc)
+	if err != nil {
+		return nil, err
+	}
+	if again, err := encodeText(text, enc); err != nil || string(again) != string(data) {
+		return nil, fmt.Errorf("%s: %w", enc, errNotRoundTrip)
+	}
+	return text, nil
+}
+
+// fixEncoded applies fix to data in enc, decoding to UTF-8 and back so the
+// file keeps its encoding. Content that cannot be decoded losslessly is
+// rejected rather than altered.
+func fixEncoded(data []byte, enc Encoding, fix fixFunc) ([]byte, error) {
+	if enc == EncodingUTF8 {
+		return fix(data), nil
+	}
+	text, err := decodeLossless(data, enc)
+	if err != nil {
+		return nil, err
+	}
+	return encodeText(fix(text), enc)
+}
@@ -0,0 +1,169 @@
+package whitespace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// encodeForTest encodes s in enc, failing the test on error
+func encodeForTest(t *testing.T, s string, enc Encoding) []byte {
+	t.Helper()
+	out, err := encodeText([]byte(s), enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestEncodingAwareFixing(t *testing.T) {
+	tests := []struct {
+		name    string
+		enc     Encoding
+		legacy  Encoding
+		bom     string
+		input   string
+		newline string
+		trimmed string
+	}{
+		{
+			name:    "utf-16le with BOM and CRLF",
+			enc:     EncodingUTF16LE,
+			bom:     "\uFEFF",
+			input:   "Windows Registry Editor Version 5.00  \r\n\r\n[HKEY_CURRENT_USER\\Software]\t\r\n\r\n\r\n",
+			newline: "Windows Registry Editor Version 5.00  \r\n\r\n[HKEY_CURRENT_USER\\Software]\t\n",
+			trimmed: "Windows Registry Editor Version 5.00\r\n\r\n[HKEY_CURRENT_USER\\Software]\r\n\r\n\r\n",
+		},
+		{
+			name:    "utf-16be with BOM",
+			enc:     EncodingUTF16BE,
+			bom:     "\uFEFF",
+			input:   "héllo  \nwörld",
+			newline: "héllo  \nwörld\n",
+			trimmed: "héllo\nwörld",
+		},
+		{
+			name:    "utf-16le without BOM",
+			enc:     EncodingUTF16LE,
+			input:   "STRINGTABLE  \nBEGIN\n",
+			newline: "STRINGTABLE  \nBEGIN\n",
+			trimmed: "STRINGTABLE\nBEGIN\n",
+		},
+		{
+			name:    "utf-16le surrogate pairs",
+			enc:     EncodingUTF16LE,
+			bom:     "\uFEFF",
+			input:   "emoji 😀 \n\n",
+			newline: "emoji 😀 \n",
+			trimmed: "emoji 😀\n\n",
+		},
+		{
+			name:    "utf-32le with BOM",
+			enc:     EncodingUTF32LE,
+			bom:     "\uFEFF",
+			input:   "wide \t\n",
+			newline: "wide \t\n",
+			trimmed: "wide\n",
+		},
+		{
+			name:    "latin-1",
+			enc:     EncodingLatin1,
+			legacy:  EncodingLatin1,
+			input:   "café crème  \nvoilà",
+			newline: "café crème  \nvoilà\n",
+			trimmed: "café crème\nvoilà",
+		},
+		{
+			name:    "windows-1252 curly quotes and undefined byte",
+			enc:     EncodingWindows1252,
+			legacy:  EncodingWindows1252,
+			input:   "“quoted” – €5 \u0081 \n\n",
+			newline: "“quoted” – €5 \u0081 \n",
+			trimmed: "“quoted” – €5 \u0081\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, fixer := range []struct {
+				name     string
+				process  func(string, Options) error
+				expected string
+			}{
+				{"newline", ProcessNewlineWithOptions, tt.newline},
+				{"trailingspace", ProcessTrailingspaceWithOptions, tt.trimmed},
+			} {
+				dir := t.TempDir()
+				path := filepath.Join(dir, "file.txt")
+				if err := os.WriteFile(path, encodeForTest(t, tt.bom+tt.input, tt.enc), 0o644); err != nil {
+					t.Fatal(err)
+				}
+
+				// Process the directory so detection is exercised too
+				if err := fixer.process(dir, Options{LegacyEncoding: tt.legacy}); err != nil {
+					t.Fatalf("%s: %v", fixer.name, err)
+				}
+
+				want := encodeForTest(t, tt.bom+fixer.expected, tt.enc)
+				if got := readFileBytes(t, path); string(got) != string(want) {
+					t.Errorf("%s: expected %q, got %q", fixer.name, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodingAwareFixing_RejectsLossyContent(t *testing.T) {
+	// An unpaired high surrogate cannot be decoded and re-encoded unchanged
+	content := []byte{0xFF, 0xFE, 'a', 0x00, 0x00, 0xD8, ' ', 0x00, '\n', 0x00}
+	path := filepath.Join(t.TempDir(), "broken.txt")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := ProcessTrailingspace(path)
+	if !errors.Is(err, errNotRoundTrip) {
+		t.Fatalf("expected round-trip error, got %v", err)
+	}
+	if got := readFileBytes(t, path); string(got) != string(content) {
+		t.Errorf("expected file to be left unchanged, got %q", got)
+	}
+}
+
+func TestSingleByteRoundTrip(t *testing.T) {
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	for _, enc := range []Encoding{EncodingLatin1, EncodingWindows1252} {
+		text, err := decodeText(all, enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := encodeText(text, enc)
+		if err != nil {
+			t.Fatalf("%s: %v", enc, err)
+		}
+		if string(again) != string(all) {
+			t.Errorf("%s: bytes did not round-trip", enc)
+		}
+	}
+}
+
+func TestParseLegacyEncoding(t *testing.T) {
+	for s, want := range map[string]Encoding{
+		"":             "",
+		"latin1":       EncodingLatin1,
+		"ISO-8859-1":   EncodingLatin1,
+		"windows-1252": EncodingWindows1252,
+		"cp1252":       EncodingWindows1252,
+	} {
+		got, err := ParseLegacyEncoding(s)
+		if err != nil || got != want {
+			t.Errorf("ParseLegacyEncoding(%q) = %q, %v; want %q", s, got, err, want)
+		}
+	}
+	if _, err := ParseLegacyEncoding("shift-jis"); err == nil {
+		t.Error("expected error for unsupported encoding")
+	}
+}
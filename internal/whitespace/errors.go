@@ -0,0 +1,82 @@
+package whitespace
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// Operations recorded in FileError
+const (
+	OpWalk    = "walk"    // reading a directory during the walk
+	OpDetect  = "detect"  // text/binary detection
+	OpProcess = "process" // running the fixer on the file
+)
+
+// FileError records a failure for a single path
+type FileError struct {
+	Path string
+	Op   string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	err := e.Err
+	// Avoid repeating the path already carried by *fs.PathError
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) && pathErr.Path == e.Path {
+		err = pathErr.Err
+	}
+	return fmt.Sprintf("%s: %s: %v", e.Path, e.Op, err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError aggregates the per-file errors collected when Options.KeepGoing is set
+type MultiError struct {
+	Errors []*FileError
+}
+
+func (m *MultiError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d file(s) failed:", len(m.Errors))
+	for _, e := range m.Errors {
+		b.WriteString("\n  ")
+		b.WriteString(e.Error())
+	}
+	return b.String()
+}
+
+func (m *MultiError) Unwrap() []error {
+	errs := make([]error, len(m.Errors))
+	for i, e := range m.Errors {
+		errs[i] = e
+	}
+	return errs
+}
+
+// errorCollector either aborts on the first error or, with KeepGoing, records it and continues
+type errorCollector struct {
+	keepGoing bool
+	errs      []*FileError
+}
+
+// add returns err unchanged when not keeping going, otherwise records it and returns nil
+func (c *errorCollector) add(path, op string, err error) error {
+	if err == nil || !c.keepGoing {
+		return err
+	}
+	c.errs = append(c.errs, &FileError{Path: path, Op: op, Err: err})
+	return nil
+}
+
+// err returns the aggregated error, or nil if nothing was recorded
+func (c *errorCollector) err() error {
+	if len(c.errs) == 0 {
+		return nil
+	}
+	return &MultiError{Errors: c.errs}
+}
@@ -0,0 +1,84 @@
+package whitespace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// failingProcessor fails on files with the given base names and records the rest
+type failingProcessor struct {
+	fail           map[string]bool
+	processedFiles []string
+}
+
+func (f *failingProcessor) process(path string) error {
+	if f.fail[filepath.Base(path)] {
+		return &os.PathError{Op: "open", Path: path, Err: os.ErrPermission}
+	}
+	f.processedFiles = append(f.processedFiles, path)
+	return nil
+}
+
+func TestKeepGoing_Disabled(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTestFileStructure(t, tmpDir)
+
+	proc := &failingProcessor{fail: map[string]bool{"code.go": true}}
+	err := processTarget(filepath.Join(tmpDir, "testdir"), Options{}, proc.process)
+	if !errors.Is(err, os.ErrPermission) {
+		t.Fatalf("expected permission error, got %v", err)
+	}
+	var multi *MultiError
+	if errors.As(err, &multi) {
+		t.Errorf("expected the first error to abort the walk, got aggregated %v", err)
+	}
+}
+
+func TestKeepGoing_CollectsErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTestFileStructure(t, tmpDir)
+
+	proc := &failingProcessor{fail: map[string]bool{"code.go": true, "readme.md": true}}
+	testDir := filepath.Join(tmpDir, "testdir")
+	err := processTarget(testDir, Options{KeepGoing: true}, proc.process)
+
+	var multi *MultiError
+	if !errors.As(err, &multi) {
+		t.Fatalf("expected *MultiError, got %v", err)
+	}
+
+	// code.go and readme.md fail in each of the 4 non-hidden directories
+	if len(multi.Errors) != 8 {
+		t.Errorf("expected 8 errors, got %d: %v", len(multi.Errors), err)
+	}
+	for _, e := range multi.Errors {
+		if e.Op != OpProcess {
+			t.Errorf("expected op %q, got %q", OpProcess, e.Op)
+		}
+		if !strings.HasPrefix(e.Path, testDir) {
+			t.Errorf("unexpected path %s", e.Path)
+		}
+	}
+	if !errors.Is(err, os.ErrPermission) {
+		t.Errorf("expected aggregated error to wrap os.ErrPermission")
+	}
+
+	// The remaining 5 text files per directory are still processed
+	if len(proc.processedFiles) != 20 {
+		t.Errorf("expected 20 processed files, got %d", len(proc.processedFiles))
+	}
+}
+
+func TestFileError_Message(t *testing.T) {
+	e := &FileError{
+		Path: "a/b.txt",
+		Op:   OpDetect,
+		Err:  &os.PathError{Op: "open", Path: "a/b.txt", Err: os.ErrPermission},
+	}
+	if got, want := e.Error(), "a/b.txt: detect: permission denied"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,93 @@
+package whitespace
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileType describes a kind of file selectable with --type
+type FileType struct {
+	Name       string
+	Aliases    []string
+	Extensions []string // including the leading dot, matched case-insensitively
+	Filenames  []string // exact base names
+	Patterns   []string // glob patterns on the base name, e.g. "Dockerfile.*"
+}
+
+// fileTypes is the built-in registry used by --type
+var fileTypes = []FileType{
+	{Name: "c", Extensions: []string{".c", ".h"}},
+	{Name: "cpp", Aliases: []string{"c++"}, Extensions: []string{".cc", ".cpp", ".cxx", ".hh", ".hpp", ".hxx"}},
+	{Name: "css", Extensions: []string{".css", ".scss", ".sass", ".less"}},
+	{Name: "docker", Aliases: []string{"dockerfile"}, Extensions: []string{".dockerfile"}, Filenames: []string{"Dockerfile", "Containerfile"}, Patterns: []string{"Dockerfile.*", "Containerfile.*"}},
+	{Name: "go", Extensions: []string{".go"}, Filenames: []string{"go.mod", "go.work"}},
+	{Name: "html", Extensions: []string{".html", ".htm"}},
+	{Name: "java", Extensions: []string{".java"}},
+	{Name: "js", Aliases: []string{"javascript"}, Extensions: []string{".js", ".mjs", ".cjs", ".jsx"}},
+	{Name: "json", Extensions: []string{".json"}},
+	{Name: "make", Aliases: []string{"makefile"}, Extensions: []string{".mk", ".mak"}, Filenames: []string{"Makefile", "makefile", "GNUmakefile"}},
+	{Name: "md", Aliases: []string{"markdown"}, Extensions: []string{".md", ".markdown"}},
+	{Name: "proto", Extensions: []string{".proto"}},
+	{Name: "py", Aliases: []string{"python"}, Extensions: []string{".py", ".pyi"}},
+	{Name: "rb", Aliases: []string{"ruby"}, Extensions: []string{".rb"}, Filenames: []string{"Gemfile", "Rakefile"}},
+	{Name: "rust", Aliases: []string{"rs"}, Extensions: []string{".rs"}},
+	{Name: "sh", Aliases: []string{"shell"}, Extensions: []string{".sh", ".bash", ".zsh"}, Filenames: []string{".bashrc", ".zshrc", ".profile"}},
+	{Name: "sql", Extensions: []string{".sql"}},
+	{Name: "terraform", Aliases: []string{"tf"}, Extensions: []string{".tf", ".tfvars"}},
+	{Name: "toml", Extensions: []string{".toml"}},
+	{Name: "ts", Aliases: []string{"typescript"}, Extensions: []string{".ts", ".tsx", ".mts", ".cts"}},
+	{Name: "txt", Aliases: []string{"text"}, Extensions: []string{".txt"}},
+	{Name: "xml", Extensions: []string{".xml", ".xsd", ".xsl"}},
+	{Name: "yaml", Aliases: []string{"yml"}, Extensions: []string{".yaml", ".yml"}},
+}
+
+// FileTypes returns the names of the built-in file types, sorted
+func FileTypes() []string {
+	names := make([]string, len(fileTypes))
+	for i, ft := range fileTypes {
+		names[i] = ft.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupFileType finds a file type by name or alias
+func LookupFileType(name string) (*FileType, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for i := range fileTypes {
+		ft := &fileTypes[i]
+		if ft.Name == name {
+			return ft, nil
+		}
+		for _, alias := range ft.Aliases {
+			if alias == name {
+				return ft, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown file type %q (known types: %s)", name, strings.Join(FileTypes(), ", "))
+}
+
+// Matches reports whether the file at path is of this type
+func (ft *FileType) Matches(path string) bool {
+	base := filepath.Base(path)
+	ext := strings.ToLower(filepath.Ext(base))
+	for _, e := range ft.Extensions {
+		if ext == e {
+			return true
+		}
+	}
+	for _, name := range ft.Filenames {
+		if base == name {
+			return true
+		}
+	}
+	for _, pattern := range ft.Patterns {
+		if ok, _ := filepath.Match(pattern, base); ok {
+			return true
+		}
+	}
+	return false
+}
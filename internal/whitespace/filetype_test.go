@@ -0,0 +1,121 @@
+package whitespace
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestFileTypeMatches(t *testing.T) {
+	tests := []struct {
+		typ     string
+		path    string
+		matches bool
+	}{
+		{"go", "cmd/main.go", true},
+		{"go", "go.mod", true},
+		{"go", "go.sum", false},
+		{"yaml", "deploy/values.YML", true},
+		{"yml", "config.yaml", true},
+		{"md", "README.md", true},
+		{"markdown", "docs/guide.markdown", true},
+		{"make", "Makefile", true},
+		{"make", "rules.mk", true},
+		{"make", "Makefile.bak", false},
+		{"docker", "Dockerfile", true},
+		{"docker", "build/Dockerfile.dev", true},
+		{"docker", "app.dockerfile", true},
+		{"docker", "dockerfile.txt", false},
+		{"sh", ".zshrc", true},
+	}
+
+	for _, tt := range tests {
+		ft, err := LookupFileType(tt.typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ft.Matches(tt.path); got != tt.matches {
+			t.Errorf("type %s, path %s: expected %v, got %v", tt.typ, tt.path, tt.matches, got)
+		}
+	}
+
+	if _, err := LookupFileType("cobol"); err == nil {
+		t.Error("expected error for unknown file type")
+	}
+}
+
+func TestFileSelection_IncludeAndTypes(t *testing.T) {
+	// Non-hidden directories of createTestFileStructure, relative to testdir
+	dirs := []string{"", "bin/", "build/", "subdir/"}
+	inDirs := func(dirs []string, names ...string) []string {
+		var paths []string
+		for _, d := range dirs {
+			for _, n := range names {
+				paths = append(paths, d+n)
+			}
+		}
+		return paths
+	}
+
+	tests := []struct {
+		name     string
+		opts     Options
+		expected []string
+	}{
+		{
+			name:     "include pattern",
+			opts:     Options{IncludePatterns: []string{"*.go"}},
+			expected: inDirs(dirs, "code.go"),
+		},
+		{
+			name:     "include path pattern",
+			opts:     Options{IncludePatterns: []string{"subdir/*.md"}},
+			expected: []string{"subdir/readme.md"},
+		},
+		{
+			name:     "types",
+			opts:     Options{Types: []string{"go", "md"}},
+			expected: inDirs(dirs, "code.go", "readme.md"),
+		},
+		{
+			name:     "types and include patterns are combined",
+			opts:     Options{Types: []string{"json"}, IncludePatterns: []string{"*.sh"}},
+			expected: inDirs(dirs, "config.json", "script.sh"),
+		},
+		{
+			name:     "exclude wins over include",
+			opts:     Options{Types: []string{"go"}, ExcludePatterns: []string{"subdir"}},
+			expected: inDirs([]string{"", "bin/", "build/"}, "code.go"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			createTestFileStructure(t, tmpDir)
+			mock := &mockProcessor{}
+
+			testDir := filepath.Join(tmpDir, "testdir")
+			if err := processTarget(testDir, tt.opts, mock.process); err != nil {
+				t.Fatal(err)
+			}
+
+			got := relPaths(t, testDir, mock.processedFiles)
+			sort.Strings(tt.expected)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Fatalf("expected %v, got %v", tt.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestFileSelection_UnknownType(t *testing.T) {
+	if err := processTarget(t.TempDir(), Options{Types: []string{"nope"}}, (&mockProcessor{}).process); err == nil {
+		t.Error("expected error for unknown file type")
+	}
+}
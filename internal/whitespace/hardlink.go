@@ -0,0 +1,74 @@
+package whitespace
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// HardlinkPolicy controls how files with more than one hard link are treated.
+// Fixers always rewrite files in place, so a processed file keeps its inode
+// and the change is visible through every link.
+type HardlinkPolicy string
+
+const (
+	// HardlinksPreserve rewrites hard-linked files in place, updating every link (default)
+	HardlinksPreserve HardlinkPolicy = "preserve"
+	// HardlinksWarn rewrites hard-linked files in place and reports each one
+	HardlinksWarn HardlinkPolicy = "warn"
+	// HardlinksSkip leaves hard-linked files untouched
+	HardlinksSkip HardlinkPolicy = "skip"
+)
+
+// ParseHardlinkPolicy validates a --hardlinks value; the empty string selects HardlinksPreserve
+func ParseHardlinkPolicy(s string) (HardlinkPolicy, error) {
+	switch p := HardlinkPolicy(s); p {
+	case "":
+		return HardlinksPreserve, nil
+	case HardlinksPreserve, HardlinksWarn, HardlinksSkip:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid hardlink policy %q (want preserve, warn or skip)", s)
+}
+
+// allowHardlink applies the hard link policy to a file about to be processed
+func allowHardlink(path string, info fs.FileInfo, opts *Options) bool {
+	n := linkCount(info)
+	if n <= 1 {
+		return true
+	}
+	switch opts.Hardlinks {
+	case HardlinksSkip:
+		return false
+	case HardlinksWarn:
+		warnf(opts, "%s: has %d hard links; rewriting in place affects all of them", path, n)
+	}
+	return true
+}
+
+// isSpecial reports whether mode is a FIFO, socket or device, which are never processed
+func isSpecial(mode fs.FileMode) bool {
+	return mode&(fs.ModeNamedPipe|fs.ModeSocket|fs.ModeDevice|fs.ModeCharDevice|fs.ModeIrregular) != 0
+}
+
+// writeInPlace truncates and rewrites path without replacing its inode, so
+// hard links, ownership and permissions are preserved
+func writeInPlace(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// warnf writes a warning to opts.Warn, if set
+func warnf(opts *Options, format string, args ...any) {
+	if opts.Warn == nil {
+		return
+	}
+	fmt.Fprintf(opts.Warn, "Warning: "+format+"\n", args...)
+}
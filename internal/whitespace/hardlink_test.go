@@ -0,0 +1,107 @@
+package whitespace
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// createHardlinkPair creates a text file with trailing whitespace and a second hard link to it
+func createHardlinkPair(t *testing.T) (dir, original, link string) {
+	t.Helper()
+	dir = t.TempDir()
+	original = filepath.Join(dir, "original.txt")
+	link = filepath.Join(dir, "link.txt")
+	if err := os.WriteFile(original, []byte("content  \n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(original, link); err != nil {
+		t.Skipf("hard links not supported: %v", err)
+	}
+	info, err := os.Stat(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linkCount(info) < 2 {
+		t.Skip("link counts not available on this platform")
+	}
+	return dir, original, link
+}
+
+func TestHardlinkPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    HardlinkPolicy
+		processed bool
+		warned    bool
+	}{
+		{"default preserves", "", true, false},
+		{"preserve", HardlinksPreserve, true, false},
+		{"warn", HardlinksWarn, true, true},
+		{"skip", HardlinksSkip, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, original, link := createHardlinkPair(t)
+			var warn bytes.Buffer
+			opts := Options{Hardlinks: tt.policy, Warn: &warn}
+			if err := ProcessTrailingspaceWithOptions(dir, opts); err != nil {
+				t.Fatal(err)
+			}
+
+			expected := "content  \n"
+			if tt.processed {
+				expected = "content\n"
+			}
+			// Both names must see the same content: the inode is never replaced
+			for _, path := range []string{original, link} {
+				if got := readTrailingspaceFileContent(t, path); got != expected {
+					t.Errorf("%s: expected %q, got %q", filepath.Base(path), expected, got)
+				}
+			}
+			info, err := os.Stat(original)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if linkCount(info) != 2 {
+				t.Errorf("expected link count to stay 2, got %d", linkCount(info))
+			}
+
+			if warned := strings.Contains(warn.String(), "hard links"); warned != tt.warned {
+				t.Errorf("expected warned=%v, got output %q", tt.warned, warn.String())
+			}
+		})
+	}
+}
+
+func TestHardlinkPolicy_SingleTarget(t *testing.T) {
+	_, original, link := createHardlinkPair(t)
+	if err := ProcessTrailingspaceWithOptions(link, Options{Hardlinks: HardlinksSkip}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTrailingspaceFileContent(t, original); got != "content  \n" {
+		t.Errorf("expected skipped hard link to be unchanged, got %q", got)
+	}
+
+	// The same named target is fixed under the default policy
+	if err := ProcessTrailingspaceWithOptions(link, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTrailingspaceFileContent(t, original); got != "content\n" {
+		t.Errorf("expected hard link to be fixed without skip, got %q", got)
+	}
+}
+
+func TestParseHardlinkPolicy(t *testing.T) {
+	for _, s := range []string{"", "preserve", "warn", "skip"} {
+		if _, err := ParseHardlinkPolicy(s); err != nil {
+			t.Errorf("ParseHardlinkPolicy(%q) failed: %v", s, err)
+		}
+	}
+	if _, err := ParseHardlinkPolicy("break"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
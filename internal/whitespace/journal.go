@@ -0,0 +1,256 @@
+package whitespace
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultBackupSuffix is appended to backup copies when --backup is given without a value
+const DefaultBackupSuffix = "~"
+
+// OpUndo is recorded in FileError when a journaled file cannot be restored
+const OpUndo = "undo"
+
+const (
+	journalManifest = "manifest.jsonl" // one journalEntry per modified file, in order
+	journalUndone   = "UNDONE"         // marker written once a run has been restored
+)
+
+// writeBackup saves original next to path using suffix, with the same
+// permissions. An existing file is never replaced: it may be the user's own,
+// or the backup of the true original from an earlier run.
+func writeBackup(path, suffix string, original []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path+suffix, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("backup %s already exists; move it away or choose another --backup suffix", path+suffix)
+	} else if err != nil {
+		return err
+	}
+	if _, err := f.Write(original); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// journalEntry describes one modified file in a run's manifest
+type journalEntry struct {
+	Path string      `json:"path"`   // absolute path of the modified file
+	Blob string      `json:"blob"`   // file in the run directory holding the original bytes
+	Mode fs.FileMode `json:"mode"`   // permissions, used if the file has to be recreated
+	Sum  string      `json:"sha256"` // hash of the content written by the fix
+}
+
+// Journal records the original bytes of every file modified during one run,
+// so the run can be reverted with Undo. Each run is a directory under Dir
+// named by Run; runs sort chronologically by name. A Journal is safe for
+// concurrent use, as when several targets are watched.
+type Journal struct {
+	Dir string
+	Run string
+
+	mu       sync.Mutex
+	manifest *os.File
+	count    int
+}
+
+// NewJournal starts a new run in the journal directory dir, creating it if needed
+func NewJournal(dir string) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	base := time.Now().UTC().Format("20060102T150405.000000Z")
+	run := base
+	for i := 1; ; i++ {
+		err := os.Mkdir(filepath.Join(dir, run), 0o700)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		run = base + "-" + strconv.Itoa(i)
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, run, journalManifest), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &Journal{Dir: dir, Run: run, manifest: f}, nil
+}
+
+// Record saves the original content of path before it is overwritten with fixed
+func (j *Journal) Record(path string, original, fixed []byte) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.count++
+	blob := fmt.Sprintf("%06d.orig", j.count)
+	if err := os.WriteFile(filepath.Join(j.Dir, j.Run, blob), original, 0o600); err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(fixed)
+	line, err := json.Marshal(journalEntry{
+		Path: abs,
+		Blob: blob,
+		Mode: info.Mode().Perm(),
+		Sum:  hex.EncodeToString(sum[:]),
+	})
+	if err != nil {
+		return err
+	}
+	// Append immediately so an interrupted run can still be undone
+	_, err = j.manifest.Write(append(line, '\n'))
+	return err
+}
+
+// Close finishes the run. Runs that modified nothing are removed.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.manifest.Close(); err != nil {
+		return err
+	}
+	if j.count == 0 {
+		return os.RemoveAll(filepath.Join(j.Dir, j.Run))
+	}
+	return nil
+}
+
+// Count returns the number of files recorded so far
+func (j *Journal) Count() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.count
+}
+
+// JournalRuns lists the runs in dir that have not been undone, oldest first
+func JournalRuns(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var runs []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, e.Name(), journalUndone)); err == nil {
+			continue
+		}
+		runs = append(runs, e.Name())
+	}
+	sort.Strings(runs)
+	return runs, nil
+}
+
+// Undo restores every file recorded in run (the latest run if run is empty)
+// and marks the run as undone. Files changed since the run are left alone
+// and reported unless force is set. It returns the run restored and the
+// paths written.
+func Undo(dir, run string, force bool) (string, []string, error) {
+	if run == "" {
+		runs, err := JournalRuns(dir)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(runs) == 0 {
+			return "", nil, errors.New("no runs to undo in " + dir)
+		}
+		run = runs[len(runs)-1]
+	}
+
+	runDir := filepath.Join(dir, run)
+	if _, err := os.Stat(filepath.Join(runDir, journalUndone)); err == nil {
+		return run, nil, errors.New("run already undone: " + run)
+	}
+	entries, err := readManifest(filepath.Join(runDir, journalManifest))
+	if err != nil {
+		return run, nil, err
+	}
+
+	errs := &errorCollector{keepGoing: true}
+	var restored []string
+	// Restore newest first so a file modified twice ends up with its earliest content
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if err := restoreEntry(runDir, e, force); err != nil {
+			errs.add(e.Path, OpUndo, err)
+			continue
+		}
+		restored = append(restored, e.Path)
+	}
+	if err := errs.err(); err != nil {
+		return run, restored, err
+	}
+	return run, restored, os.WriteFile(filepath.Join(runDir, journalUndone), nil, 0o600)
+}
+
+// readManifest parses a run's manifest
+func readManifest(path string) ([]journalEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []journalEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// restoreEntry writes a journaled original back to its path
+func restoreEntry(runDir string, e journalEntry, force bool) error {
+	original, err := os.ReadFile(filepath.Join(runDir, e.Blob))
+	if err != nil {
+		return err
+	}
+
+	current, err := os.ReadFile(e.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return os.WriteFile(e.Path, original, e.Mode)
+	}
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(current, original) {
+		return nil
+	}
+	if sum := sha256.Sum256(current); !force && hex.EncodeToString(sum[:]) != e.Sum {
+		return errors.New("modified since the run; use --force to restore anyway")
+	}
+	return writeInPlace(e.Path, original)
+}
@@ -0,0 +1,234 @@
+package whitespace
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates the given files under dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBackup(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"dirty.txt": "dirty  \n",
+		"clean.txt": "clean\n",
+	})
+
+	if err := ProcessTrailingspaceWithOptions(dir, Options{Backup: ".orig"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readTrailingspaceFileContent(t, filepath.Join(dir, "dirty.txt.orig")); got != "dirty  \n" {
+		t.Errorf("expected backup to hold original content, got %q", got)
+	}
+	if got := readTrailingspaceFileContent(t, filepath.Join(dir, "dirty.txt")); got != "dirty\n" {
+		t.Errorf("expected fixed content, got %q", got)
+	}
+	// Unmodified files are not backed up
+	if _, err := os.Stat(filepath.Join(dir, "clean.txt.orig")); !os.IsNotExist(err) {
+		t.Errorf("expected no backup for unmodified file, got %v", err)
+	}
+}
+
+func TestBackup_RunTwice(t *testing.T) {
+	for _, suffix := range []string{DefaultBackupSuffix, ".orig"} {
+		t.Run(suffix, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{"a.txt": "a  \n"})
+			backup := filepath.Join(dir, "a.txt"+suffix)
+
+			// A second --backup run must not back up or fix the backup
+			for range 2 {
+				if err := ProcessTrailingspaceWithOptions(dir, Options{Backup: suffix}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := os.Stat(backup + suffix); !os.IsNotExist(err) {
+				t.Errorf("expected no backup of the backup, got %v", err)
+			}
+			if got := readTrailingspaceFileContent(t, backup); got != "a  \n" {
+				t.Errorf("expected backup to keep the original content, got %q", got)
+			}
+			// Nor a later run without --backup, for the default suffix
+			if suffix == DefaultBackupSuffix {
+				if err := ProcessTrailingspaceWithOptions(dir, Options{}); err != nil {
+					t.Fatal(err)
+				}
+				if got := readTrailingspaceFileContent(t, backup); got != "a  \n" {
+					t.Errorf("expected backup to survive a plain run, got %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestBackup_Existing(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.txt":  "a  \n",
+		"a.txt~": "mine\n",
+		"b.txt":  "b  \n",
+	})
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+
+	// A file that is not ours is left alone, and so is the file it backs up
+	err := ProcessTrailingspaceWithOptions(dir, Options{Backup: DefaultBackupSuffix, KeepGoing: true})
+	if err == nil || !strings.Contains(err.Error(), "a.txt~ already exists") {
+		t.Errorf("expected an error for the existing backup, got %v", err)
+	}
+	if got := readTrailingspaceFileContent(t, a+"~"); got != "mine\n" {
+		t.Errorf("expected the existing file to be kept, got %q", got)
+	}
+	if got := readTrailingspaceFileContent(t, a); got != "a  \n" {
+		t.Errorf("expected a.txt to be left unfixed, got %q", got)
+	}
+	if got := readTrailingspaceFileContent(t, b); got != "b\n" {
+		t.Errorf("expected b.txt to be fixed, got %q", got)
+	}
+
+	// The backup of the true original survives a later change and run
+	writeFiles(t, dir, map[string]string{"b.txt": "b\nedited  \n"})
+	if err := ProcessTrailingspaceWithOptions(b, Options{Backup: DefaultBackupSuffix}); err == nil {
+		t.Error("expected an error for the earlier backup")
+	}
+	if got := readTrailingspaceFileContent(t, b+"~"); got != "b  \n" {
+		t.Errorf("expected the first backup to be kept, got %q", got)
+	}
+}
+
+func TestJournalUndo(t *testing.T) {
+	dir := t.TempDir()
+	journalDir := filepath.Join(t.TempDir(), "journal")
+	writeFiles(t, dir, map[string]string{
+		"a.txt":     "a  ",
+		"sub/b.txt": "b\n\n\n",
+		"clean.txt": "clean\n",
+	})
+
+	// First run: newline
+	j1, err := NewJournal(journalDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ProcessNewlineWithOptions(dir, Options{Journal: j1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := j1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if j1.Count() != 2 {
+		t.Errorf("expected 2 journaled files, got %d", j1.Count())
+	}
+
+	// Second run: trailingspace
+	j2, err := NewJournal(journalDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ProcessTrailingspaceWithOptions(dir, Options{Journal: j2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := j2.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A run that changes nothing leaves no trace
+	j3, err := NewJournal(journalDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ProcessTrailingspaceWithOptions(dir, Options{Journal: j3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := j3.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := JournalRuns(journalDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0] != j1.Run || runs[1] != j2.Run {
+		t.Fatalf("expected runs [%s %s], got %v", j1.Run, j2.Run, runs)
+	}
+
+	// Undo the latest run (trailingspace)
+	run, restored, err := Undo(journalDir, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run != j2.Run || len(restored) != 1 {
+		t.Errorf("expected to restore 1 file from %s, got %s %v", j2.Run, run, restored)
+	}
+	if got := readTrailingspaceFileContent(t, filepath.Join(dir, "a.txt")); got != "a  \n" {
+		t.Errorf("expected a.txt after newline run, got %q", got)
+	}
+
+	// Undo the named first run
+	if _, _, err := Undo(journalDir, j1.Run, false); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"a.txt": "a  ", "sub/b.txt": "b\n\n\n", "clean.txt": "clean\n"}
+	for name, content := range expected {
+		if got := readTrailingspaceFileContent(t, filepath.Join(dir, name)); got != content {
+			t.Errorf("%s: expected %q, got %q", name, content, got)
+		}
+	}
+
+	if runs, _ := JournalRuns(journalDir); len(runs) != 0 {
+		t.Errorf("expected no runs left to undo, got %v", runs)
+	}
+	if _, _, err := Undo(journalDir, j1.Run, false); err == nil {
+		t.Error("expected error undoing a run twice")
+	}
+}
+
+func TestJournalUndo_ModifiedSinceRun(t *testing.T) {
+	dir := t.TempDir()
+	journalDir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	writeFiles(t, dir, map[string]string{"a.txt": "a  \n"})
+
+	j, err := NewJournal(journalDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ProcessTrailingspaceWithOptions(dir, Options{Journal: j}); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Edited after the run: undo must not clobber the edit
+	if err := os.WriteFile(path, []byte("edited\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Undo(journalDir, "", false); err == nil {
+		t.Fatal("expected error restoring a file modified since the run")
+	}
+	if got := readTrailingspaceFileContent(t, path); got != "edited\n" {
+		t.Errorf("expected edit to be kept, got %q", got)
+	}
+
+	if _, _, err := Undo(journalDir, "", true); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTrailingspaceFileContent(t, path); got != "a  \n" {
+		t.Errorf("expected forced restore, got %q", got)
+	}
+}
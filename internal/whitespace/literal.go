@@ -0,0 +1,289 @@
+package whitespace
+
+import (
+	"regexp"
+	"strings"
+)
+
+// literalLexer reports, for each line of text (as split on "\n"), whether
+// the line ends inside a multi-line string literal (or a diff hunk), where
+// trailing whitespace is data and must not be removed. A nil result
+// protects nothing.
+type literalLexer func(text string) []bool
+
+// literalLexers maps file type names to their lexers. They only need to
+// understand enough of each language to find where literals start and end.
+var literalLexers = map[string]literalLexer{
+	"go":   goLiterals,
+	"py":   pythonLiterals,
+	"sh":   shellLiterals,
+	"yaml": yamlLiterals,
+}
+
+// literalLexerFor returns the lexer for the file at path. Files of other
+// types are checked for unified diff content.
+func literalLexerFor(path string) literalLexer {
+	if isDiffPath(path) {
+		return diffHunks
+	}
+	for name, lex := range literalLexers {
+		if ft, err := LookupFileType(name); err == nil && ft.Matches(path) {
+			return lex
+		}
+	}
+	return sniffDiff
+}
+
+// literalScanner walks text byte by byte, keeping track of the current line
+type literalScanner struct {
+	text      string
+	i         int
+	line      int
+	protected []bool
+}
+
+func newLiteralScanner(text string) *literalScanner {
+	return &literalScanner{text: text, protected: make([]bool, strings.Count(text, "\n")+1)}
+}
+
+// advance moves past one byte, marking the line as protected if a newline
+// is passed while inside a literal
+func (s *literalScanner) advance(inLiteral bool) {
+	if s.text[s.i] == '\n' {
+		if inLiteral {
+			s.protected[s.line] = true
+		}
+		s.line++
+	}
+	s.i++
+}
+
+// skipUntil advances past the next occurrence of end, or to the end of the
+// text. With escapes, a backslash makes the following byte literal.
+func (s *literalScanner) skipUntil(end string, inLiteral, escapes bool) {
+	for s.i < len(s.text) {
+		if strings.HasPrefix(s.text[s.i:], end) {
+			s.i += len(end)
+			return
+		}
+		if escapes && s.text[s.i] == '\\' && s.i+1 < len(s.text) {
+			s.advance(inLiteral)
+		}
+		s.advance(inLiteral)
+	}
+}
+
+// skipLine advances to the next newline without consuming it
+func (s *literalScanner) skipLine() {
+	if n := strings.IndexByte(s.text[s.i:], '\n'); n >= 0 {
+		s.i += n
+	} else {
+		s.i = len(s.text)
+	}
+}
+
+// goLiterals protects raw string literals, which are the only Go literals
+// that can span lines
+func goLiterals(text string) []bool {
+	s := newLiteralScanner(text)
+	for s.i < len(text) {
+		switch {
+		case strings.HasPrefix(text[s.i:], "//"):
+			s.skipLine()
+		case strings.HasPrefix(text[s.i:], "/*"):
+			s.i += 2
+			s.skipUntil("*/", false, false)
+		case text[s.i] == '"' || text[s.i] == '\'':
+			quote := text[s.i : s.i+1]
+			s.i++
+			s.skipUntilOnLine(quote)
+		case text[s.i] == '`':
+			s.i++
+			s.skipUntil("`", true, false)
+		default:
+			s.advance(false)
+		}
+	}
+	return s.protected
+}
+
+// skipUntilOnLine skips a single-line literal closed by quote, honoring
+// backslash escapes; an unterminated literal ends at the newline
+func (s *literalScanner) skipUntilOnLine(quote string) {
+	for s.i < len(s.text) && s.text[s.i] != '\n' {
+		if s.text[s.i] == '\\' && s.i+1 < len(s.text) {
+			s.advance(false)
+		} else if strings.HasPrefix(s.text[s.i:], quote) {
+			s.i += len(quote)
+			return
+		}
+		s.advance(false)
+	}
+}
+
+// pythonLiterals protects triple-quoted strings. A string prefix such as r,
+// b or f does not change where a string ends, so it needs no handling.
+func pythonLiterals(text string) []bool {
+	s := newLiteralScanner(text)
+	for s.i < len(text) {
+		rest := text[s.i:]
+		switch {
+		case rest[0] == '#':
+			s.skipLine()
+		case strings.HasPrefix(rest, `"""`) || strings.HasPrefix(rest, `'''`):
+			quote := rest[:3]
+			s.i += 3
+			s.skipUntil(quote, true, true)
+		case rest[0] == '"' || rest[0] == '\'':
+			s.i++
+			s.skipUntilOnLine(rest[:1])
+		default:
+			s.advance(false)
+		}
+	}
+	return s.protected
+}
+
+// heredoc is a here-document whose body starts on the next line
+type heredoc struct {
+	delimiter string
+	stripTabs bool // <<- allows the body and delimiter to be indented with tabs
+}
+
+// shellLiterals protects here-document bodies and quoted strings that span
+// lines. Arithmetic expansions are skipped so "<<" there is not mistaken for
+// a here-document.
+func shellLiterals(text string) []bool {
+	s := newLiteralScanner(text)
+	var pending []heredoc
+	for s.i < len(text) {
+		rest := text[s.i:]
+		switch {
+		case rest[0] == '\n':
+			s.advance(false)
+			for _, h := range pending {
+				s.skipHeredoc(h)
+			}
+			pending = nil
+		case rest[0] == '\\' && len(rest) > 1:
+			s.advance(false)
+			s.advance(false)
+		case rest[0] == '#' && (s.i == 0 || strings.ContainsRune(" \t\n;&|()", rune(text[s.i-1]))):
+			s.skipLine()
+		case rest[0] == '\'':
+			// $'...' strings allow backslash escapes, plain single quotes do not
+			escapes := s.i > 0 && text[s.i-1] == '$'
+			s.i++
+			s.skipUntil("'", true, escapes)
+		case rest[0] == '"':
+			s.i++
+			s.skipUntil(`"`, true, true)
+		case strings.HasPrefix(rest, "(("):
+			s.i += 2
+			s.skipUntil("))", false, false)
+		case strings.HasPrefix(rest, "<<<"):
+			s.i += 3
+		case strings.HasPrefix(rest, "<<"):
+			s.i += 2
+			if h, ok := s.heredocOperator(); ok {
+				pending = append(pending, h)
+			}
+		default:
+			s.advance(false)
+		}
+	}
+	return s.protected
+}
+
+// heredocOperator parses the rest of a "<<" operator: an optional "-" and
+// the delimiter word, from which quotes and backslashes are removed
+func (s *literalScanner) heredocOperator() (heredoc, bool) {
+	var h heredoc
+	if s.i < len(s.text) && s.text[s.i] == '-' {
+		h.stripTabs = true
+		s.i++
+	}
+	for s.i < len(s.text) && (s.text[s.i] == ' ' || s.text[s.i] == '\t') {
+		s.i++
+	}
+	var word strings.Builder
+	for s.i < len(s.text) && !strings.ContainsRune(" \t\n;&|<>()", rune(s.text[s.i])) {
+		c := s.text[s.i]
+		if c == '\'' || c == '"' {
+			end := strings.IndexByte(s.text[s.i+1:], c)
+			if end < 0 || strings.Contains(s.text[s.i+1:s.i+1+end], "\n") {
+				return heredoc{}, false
+			}
+			word.WriteString(s.text[s.i+1 : s.i+1+end])
+			s.i += end + 2
+			continue
+		}
+		if c != '\\' {
+			word.WriteByte(c)
+		}
+		s.i++
+	}
+	h.delimiter = word.String()
+	return h, h.delimiter != ""
+}
+
+// skipHeredoc consumes the body of h, starting at the beginning of a line,
+// through its delimiter line. Body lines are protected.
+func (s *literalScanner) skipHeredoc(h heredoc) {
+	for s.i < len(s.text) {
+		end := strings.IndexByte(s.text[s.i:], '\n')
+		line := s.text[s.i:]
+		if end >= 0 {
+			line = line[:end]
+		}
+		if h.stripTabs {
+			line = strings.TrimLeft(line, "\t")
+		}
+		if line == h.delimiter {
+			s.skipLine()
+			if s.i < len(s.text) {
+				s.advance(false)
+			}
+			return
+		}
+		if end < 0 {
+			s.i = len(s.text)
+			return
+		}
+		s.i += end
+		s.advance(true)
+	}
+}
+
+// yamlBlockScalar matches a line introducing a literal (|) or folded (>)
+// block scalar, with optional chomping and indentation indicators
+var yamlBlockScalar = regexp.MustCompile(`(?:^|:|^\s*-|^---)[ \t]+[|>][-+1-9]{0,2}[ \t]*(?:#.*)?$|^[|>][-+1-9]{0,2}[ \t]*$`)
+
+// yamlLiterals protects the content of block scalars, which keeps trailing
+// spaces in both literal and folded style. The content is every following
+// line that is blank or indented more than the line introducing it.
+func yamlLiterals(text string) []bool {
+	lines := strings.Split(text, "\n")
+	protected := make([]bool, len(lines))
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSuffix(lines[i], "\r")
+		if !yamlBlockScalar.MatchString(line) {
+			continue
+		}
+		parent := indentation(line)
+		for i+1 < len(lines) {
+			next := strings.TrimSuffix(lines[i+1], "\r")
+			if strings.TrimSpace(next) != "" && indentation(next) <= parent {
+				break
+			}
+			i++
+			protected[i] = true
+		}
+	}
+	return protected
+}
+
+// indentation counts the leading spaces of line
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
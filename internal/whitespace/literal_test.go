@@ -0,0 +1,183 @@
+package whitespace
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os/exec"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// goStringValues returns the values of all string literals in a Go source file
+func goStringValues(t *testing.T, src string) []string {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), "x.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []string
+	ast.Inspect(f, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			v, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			values = append(values, v)
+		}
+		return true
+	})
+	return values
+}
+
+// runOutput runs a script through an interpreter and returns its output,
+// skipping the test if the interpreter is not installed
+func runOutput(t *testing.T, interpreter, script string) string {
+	t.Helper()
+	if _, err := exec.LookPath(interpreter); err != nil {
+		t.Skipf("%s not installed", interpreter)
+	}
+	out, err := exec.Command(interpreter, "-c", script).Output()
+	if err != nil {
+		t.Fatalf("%s failed: %v", interpreter, err)
+	}
+	return string(out)
+}
+
+func TestLiterals_Go(t *testing.T) {
+	src := "package x \n" +
+		"\n" +
+		"// A comment with ` and \" in it \n" +
+		"var raw = `first   \n" +
+		"second\t\n" +
+		"` \n" +
+		"var s = \"not raw `   \" \n" +
+		"var r = '`' \n" +
+		"/* block ` comment   \n" +
+		"still comment */ \n" +
+		"var raw2 = `a \n" +
+		"b` \n"
+	expected := "package x\n" +
+		"\n" +
+		"// A comment with ` and \" in it\n" +
+		"var raw = `first   \n" +
+		"second\t\n" +
+		"`\n" +
+		"var s = \"not raw `   \"\n" +
+		"var r = '`'\n" +
+		"/* block ` comment\n" +
+		"still comment */\n" +
+		"var raw2 = `a \n" +
+		"b`\n"
+
+	got := string(trailingspaceFixer("x.go", &Options{})([]byte(src)))
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	if before, after := goStringValues(t, src), goStringValues(t, got); !reflect.DeepEqual(before, after) {
+		t.Errorf("string values changed: %q -> %q", before, after)
+	}
+	if n := len(trailingspaceChecker("x.go", &Options{})([]byte(src))); n != 8 {
+		t.Errorf("expected 8 findings outside literals, got %d", n)
+	}
+}
+
+func TestLiterals_Python(t *testing.T) {
+	src := "s = '''first   \n" +
+		"second \n" +
+		"''' \n" +
+		"d = \"\"\"a \\\"\"\" b  \n" +
+		"c\"\"\"  \n" +
+		"q = \"''' not a triple\"  \n" +
+		"# comment with ''' in it  \n" +
+		"print(repr(s), repr(d), repr(q))\n"
+	expected := "s = '''first   \n" +
+		"second \n" +
+		"'''\n" +
+		"d = \"\"\"a \\\"\"\" b  \n" +
+		"c\"\"\"\n" +
+		"q = \"''' not a triple\"\n" +
+		"# comment with ''' in it\n" +
+		"print(repr(s), repr(d), repr(q))\n"
+
+	got := string(trailingspaceFixer("x.py", &Options{})([]byte(src)))
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	if before, after := runOutput(t, "python3", src), runOutput(t, "python3", got); before != after {
+		t.Errorf("output changed: %q -> %q", before, after)
+	}
+}
+
+func TestLiterals_Shell(t *testing.T) {
+	src := "cat <<EOF \n" +
+		"body line   \n" +
+		"EOF\n" +
+		"cat <<-'END' | tr a b \n" +
+		"\tquoted $body \n" +
+		"\tEND\n" +
+		"echo \"multi   \n" +
+		"line\" \n" +
+		"echo $((1 << 2)) \n" +
+		"cat <<< 'here string' \n" +
+		"# comment <<EOF  \n" +
+		"echo done \n"
+	expected := "cat <<EOF\n" +
+		"body line   \n" +
+		"EOF\n" +
+		"cat <<-'END' | tr a b\n" +
+		"\tquoted $body \n" +
+		"\tEND\n" +
+		"echo \"multi   \n" +
+		"line\"\n" +
+		"echo $((1 << 2))\n" +
+		"cat <<< 'here string'\n" +
+		"# comment <<EOF\n" +
+		"echo done\n"
+
+	got := string(trailingspaceFixer("x.sh", &Options{})([]byte(src)))
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	if before, after := runOutput(t, "bash", src), runOutput(t, "bash", got); before != after {
+		t.Errorf("output changed: %q -> %q", before, after)
+	}
+}
+
+func TestLiterals_YAML(t *testing.T) {
+	src := "script: | \n" +
+		"  echo one  \n" +
+		"\n" +
+		"    indented   \n" +
+		"folded: >- \n" +
+		"  two  \n" +
+		"list:\n" +
+		"  - |\n" +
+		"    item  \n" +
+		"  - plain  \n" +
+		"key: a | b  \n"
+	expected := "script: |\n" +
+		"  echo one  \n" +
+		"\n" +
+		"    indented   \n" +
+		"folded: >-\n" +
+		"  two  \n" +
+		"list:\n" +
+		"  - |\n" +
+		"    item  \n" +
+		"  - plain\n" +
+		"key: a | b\n"
+
+	got := string(trailingspaceFixer("x.yaml", &Options{})([]byte(src)))
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestLiterals_OtherFiles(t *testing.T) {
+	src := "text = `raw   \nstill  \n"
+	if got := string(trailingspaceFixer("notes.txt", &Options{})([]byte(src))); got != "text = `raw\nstill\n" {
+		t.Errorf("expected plain text to be trimmed, got %q", got)
+	}
+}
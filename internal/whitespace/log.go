@@ -0,0 +1,31 @@
+package whitespace
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Decisions recorded in Options.Log, as the message of each record. Every
+// record has a "path" attribute.
+const (
+	DecisionSkippedHidden      = "skipped-hidden"       // hidden directory, without IncludeHidden
+	DecisionSkippedExcluded    = "skipped-excluded"     // matched the exclude pattern in the "pattern" attribute
+	DecisionSkippedNotIncluded = "skipped-not-included" // matched no include pattern or file type
+	DecisionSkippedSymlink     = "skipped-symlink"      // symbolic link the symlink policy does not follow
+	DecisionSkippedSpecial     = "skipped-special"      // FIFO, socket or device
+	DecisionSkippedHardlink    = "skipped-hardlink"     // hard-linked file, with HardlinksSkip
+	DecisionSkippedBinary      = "skipped-binary"       // does not look like text
+	DecisionSkippedGenerated   = "skipped-generated"    // generated file, without IncludeGenerated
+	DecisionUnchanged          = "unchanged"            // processed and already clean
+	DecisionFixed              = "fixed"                // processed and rewritten
+	DecisionProblems           = "problems"             // checked, with the count in the "problems" attribute
+)
+
+// logDecision records what was done with path in opts.Log, if set. Skips
+// and clean files are logged at debug level, changes and problems at info.
+func logDecision(opts *Options, level slog.Level, decision, path string, args ...any) {
+	if opts.Log == nil {
+		return
+	}
+	opts.Log.Log(context.Background(), level, decision, append([]any{"path", path}, args...)...)
+}
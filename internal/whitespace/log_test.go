@@ -0,0 +1,95 @@
+package whitespace
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"testing"
+)
+
+// decisions runs process on a tree with a JSON logger and returns the
+// decision and attributes logged for each path, relative to dir
+func decisions(t *testing.T, dir string, process func(opts Options) error) map[string]map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	opts := Options{
+		ExcludePatterns: []string{"*.log", "build/"},
+		Log:             slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	}
+	if err := process(opts); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]map[string]any)
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var record map[string]any
+		if err := dec.Decode(&record); err != nil {
+			t.Fatal(err)
+		}
+		rel, err := filepath.Rel(dir, record["path"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[filepath.ToSlash(rel)] = record
+	}
+	return got
+}
+
+func TestLogDecisions(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"clean.txt":      "ok\n",
+		"dirty.txt":      "dirty \n",
+		"image.bin":      "\x00\x01",
+		"debug.log":      "log \n",
+		"build/out.txt":  "out \n",
+		".cache/c.txt":   "cached \n",
+		"gen.go":         "// Code generated by x. DO NOT EDIT.\n\npackage gen \n",
+		"sub/nested.txt": "nested\n",
+	})
+
+	tests := []struct {
+		path, decision, pattern string
+	}{
+		{"clean.txt", DecisionUnchanged, ""},
+		{"dirty.txt", DecisionFixed, ""},
+		{"image.bin", DecisionSkippedBinary, ""},
+		{"debug.log", DecisionSkippedExcluded, "*.log"},
+		{"build", DecisionSkippedExcluded, "build/"},
+		{".cache", DecisionSkippedHidden, ""},
+		{"gen.go", DecisionSkippedGenerated, ""},
+		{"sub/nested.txt", DecisionUnchanged, ""},
+	}
+	got := decisions(t, dir, func(opts Options) error { return ProcessTrailingspaceWithOptions(dir, opts) })
+	if len(got) != len(tests) {
+		t.Errorf("expected %d decisions, got %v", len(tests), got)
+	}
+	for _, tt := range tests {
+		record := got[tt.path]
+		if record["msg"] != tt.decision {
+			t.Errorf("%s: expected %s, got %v", tt.path, tt.decision, record)
+		}
+		if pattern, _ := record["pattern"].(string); pattern != tt.pattern {
+			t.Errorf("%s: expected pattern %q, got %v", tt.path, tt.pattern, record)
+		}
+	}
+	if level := got["dirty.txt"]["level"]; level != "INFO" {
+		t.Errorf("expected fixed files at info level, got %v", level)
+	}
+}
+
+func TestLogDecisions_Check(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"clean.txt": "ok\n", "dirty.txt": "a \nb \n"})
+	got := decisions(t, dir, func(opts Options) error {
+		_, err := CheckTrailingspaceWithOptions(dir, opts)
+		return err
+	})
+	if got["clean.txt"]["msg"] != DecisionUnchanged {
+		t.Errorf("expected clean.txt unchanged, got %v", got["clean.txt"])
+	}
+	if r := got["dirty.txt"]; r["msg"] != DecisionProblems || r["problems"] != float64(2) {
+		t.Errorf("expected 2 problems in dirty.txt, got %v", r)
+	}
+}
@@ -0,0 +1,132 @@
+package whitespace
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MarkdownMode selects when trailing whitespace follows Markdown rules
+type MarkdownMode string
+
+const (
+	// MarkdownAuto applies Markdown rules to .md and .markdown files (default)
+	MarkdownAuto MarkdownMode = "auto"
+	// MarkdownAlways applies Markdown rules to every file
+	MarkdownAlways MarkdownMode = "always"
+	// MarkdownNever treats Markdown like any other text
+	MarkdownNever MarkdownMode = "never"
+)
+
+// ParseMarkdownMode validates a --markdown value; the empty string selects MarkdownAuto
+func ParseMarkdownMode(s string) (MarkdownMode, error) {
+	switch m := MarkdownMode(s); m {
+	case "":
+		return MarkdownAuto, nil
+	case MarkdownAuto, MarkdownAlways, MarkdownNever:
+		return m, nil
+	}
+	return "", fmt.Errorf("invalid markdown mode %q (want auto, always or never)", s)
+}
+
+// HardBreakStyle selects what happens to Markdown hard line breaks
+type HardBreakStyle string
+
+const (
+	// HardBreaksPreserve keeps two trailing spaces, trimming any beyond two (default)
+	HardBreaksPreserve HardBreakStyle = "preserve"
+	// HardBreaksBackslash replaces the trailing spaces with a backslash
+	HardBreaksBackslash HardBreakStyle = "backslash"
+)
+
+// ParseHardBreakStyle validates a --markdown-hard-breaks value; the empty string selects HardBreaksPreserve
+func ParseHardBreakStyle(s string) (HardBreakStyle, error) {
+	switch h := HardBreakStyle(s); h {
+	case "":
+		return HardBreaksPreserve, nil
+	case HardBreaksPreserve, HardBreaksBackslash:
+		return h, nil
+	}
+	return "", fmt.Errorf("invalid hard break style %q (want preserve or backslash)", s)
+}
+
+// CodeBlockPolicy selects how lines inside fenced Markdown code blocks are treated
+type CodeBlockPolicy string
+
+const (
+	// CodeBlocksTrim removes trailing whitespace in code blocks like in plain text (default)
+	CodeBlocksTrim CodeBlockPolicy = "trim"
+	// CodeBlocksPreserve leaves code block contents untouched
+	CodeBlocksPreserve CodeBlockPolicy = "preserve"
+)
+
+// ParseCodeBlockPolicy validates a --markdown-code-blocks value; the empty string selects CodeBlocksTrim
+func ParseCodeBlockPolicy(s string) (CodeBlockPolicy, error) {
+	switch c := CodeBlockPolicy(s); c {
+	case "":
+		return CodeBlocksTrim, nil
+	case CodeBlocksTrim, CodeBlocksPreserve:
+		return c, nil
+	}
+	return "", fmt.Errorf("invalid code block policy %q (want trim or preserve)", s)
+}
+
+// markdownFileType recognizes files that MarkdownAuto applies to
+var markdownFileType, _ = LookupFileType("md")
+
+// isMarkdown reports whether Markdown rules apply to the file at path
+func isMarkdown(path string, opts *Options) bool {
+	switch opts.Markdown {
+	case MarkdownAlways:
+		return true
+	case MarkdownNever:
+		return false
+	}
+	return markdownFileType.Matches(path)
+}
+
+var (
+	// codeFence matches the opening or closing fence of a fenced code block
+	codeFence = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	// atxHeading matches a heading line, where trailing spaces are never a line break
+	atxHeading = regexp.MustCompile(`^ {0,3}#{1,6}([ \t]|$)`)
+)
+
+// markdownLines tracks fenced code blocks while trimming a Markdown document
+type markdownLines struct {
+	hardBreaks HardBreakStyle
+	codeBlocks CodeBlockPolicy
+	fence      string // opening fence of the code block being read, if any
+}
+
+// fix returns the fixed form of line (without its CR), given the line that
+// follows it. Two or more trailing spaces after text, followed by another
+// line of the paragraph, are a hard line break: they are kept as exactly two
+// spaces or converted to a backslash. Other trailing whitespace is removed.
+func (m *markdownLines) fix(line, next string) string {
+	fence := codeFence.FindStringSubmatch(line)
+	if m.fence != "" {
+		// A closing fence uses the same character, is at least as long and has no info string
+		if fence != nil && fence[1][0] == m.fence[0] && len(fence[1]) >= len(m.fence) &&
+			strings.TrimSpace(line[len(fence[0]):]) == "" {
+			m.fence = ""
+		} else if m.codeBlocks == CodeBlocksPreserve {
+			return line
+		}
+		return strings.TrimRight(line, " \t")
+	}
+	if fence != nil {
+		m.fence = fence[1]
+		return strings.TrimRight(line, " \t")
+	}
+
+	text := strings.TrimRight(line, " \t")
+	spaces := len(line) - len(strings.TrimRight(line, " "))
+	if spaces < 2 || strings.TrimSpace(text) == "" || strings.TrimSpace(next) == "" || atxHeading.MatchString(line) {
+		return text
+	}
+	if m.hardBreaks == HardBreaksBackslash && !strings.HasSuffix(text, `\`) {
+		return text + `\`
+	}
+	return text + "  "
+}
@@ -0,0 +1,142 @@
+package whitespace
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMarkdownTrailingspace(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		expected   string
+		hardBreaks HardBreakStyle
+		codeBlocks CodeBlockPolicy
+	}{
+		{
+			name:     "hard break preserved",
+			input:    "first line  \nsecond line\n",
+			expected: "first line  \nsecond line\n",
+		},
+		{
+			name:     "hard break longer than two spaces",
+			input:    "first line     \nsecond line\n",
+			expected: "first line  \nsecond line\n",
+		},
+		{
+			name:     "single space and tabs trimmed",
+			input:    "one \ntwo\t\nthree \t\nfour\n",
+			expected: "one\ntwo\nthree\nfour\n",
+		},
+		{
+			name:     "spaces at end of paragraph trimmed",
+			input:    "last line  \n\nnext paragraph  \n",
+			expected: "last line\n\nnext paragraph\n",
+		},
+		{
+			name:     "whitespace-only line trimmed",
+			input:    "a\n   \nb\n",
+			expected: "a\n\nb\n",
+		},
+		{
+			name:     "heading trimmed",
+			input:    "# Title  \ntext\n",
+			expected: "# Title\ntext\n",
+		},
+		{
+			name:     "CRLF hard break",
+			input:    "first  \r\nsecond\r\n",
+			expected: "first  \r\nsecond\r\n",
+		},
+		{
+			name:       "backslash hard breaks",
+			input:      "first  \nsecond   \nthird\n",
+			expected:   "first\\\nsecond\\\nthird\n",
+			hardBreaks: HardBreaksBackslash,
+		},
+		{
+			name:     "code block trimmed by default",
+			input:    "```sh\necho hi  \n```\ntext  \nmore\n",
+			expected: "```sh\necho hi\n```\ntext  \nmore\n",
+		},
+		{
+			name:       "code block preserved",
+			input:      "```sh  \necho hi  \n  ~~~ \n```\ntext \n",
+			expected:   "```sh\necho hi  \n  ~~~ \n```\ntext\n",
+			codeBlocks: CodeBlocksPreserve,
+		},
+		{
+			name:       "longer tilde fence closes",
+			input:      "~~~\nkeep \n~~~~\nafter \n",
+			expected:   "~~~\nkeep \n~~~~\nafter\n",
+			codeBlocks: CodeBlocksPreserve,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &Options{Markdown: MarkdownAuto, HardBreaks: tt.hardBreaks, CodeBlocks: tt.codeBlocks}
+			if got := string(trailingspaceFixer("doc.md", opts)([]byte(tt.input))); got != tt.expected {
+				t.Errorf("fix: expected %q, got %q", tt.expected, got)
+			}
+			findings := trailingspaceChecker("doc.md", opts)([]byte(tt.input))
+			if changed := tt.input != tt.expected; changed != (len(findings) > 0) {
+				t.Errorf("check: expected findings = %v, got %v", changed, findings)
+			}
+		})
+	}
+}
+
+func TestMarkdownMode(t *testing.T) {
+	input := "hard break  \nnext\n"
+	tests := []struct {
+		mode MarkdownMode
+		path string
+		kept bool
+	}{
+		{"", "README.md", true},
+		{MarkdownAuto, "notes.markdown", true},
+		{MarkdownAuto, "notes.txt", false},
+		{MarkdownAlways, "notes.txt", true},
+		{MarkdownNever, "README.md", false},
+	}
+	for _, tt := range tests {
+		got := string(trailingspaceFixer(tt.path, &Options{Markdown: tt.mode})([]byte(input)))
+		if kept := got == input; kept != tt.kept {
+			t.Errorf("%s with mode %q: expected hard break kept = %v, got %q", tt.path, tt.mode, tt.kept, got)
+		}
+	}
+}
+
+func TestMarkdownTrailingspace_ProcessDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"README.md": "line one  \nline two \n",
+		"notes.txt": "line one  \nline two \n",
+	})
+	if err := ProcessTrailingspaceWithOptions(dir, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTrailingspaceFileContent(t, filepath.Join(dir, "README.md")); got != "line one  \nline two\n" {
+		t.Errorf("README.md: got %q", got)
+	}
+	if got := readTrailingspaceFileContent(t, filepath.Join(dir, "notes.txt")); got != "line one\nline two\n" {
+		t.Errorf("notes.txt: got %q", got)
+	}
+}
+
+func TestParseMarkdownOptions(t *testing.T) {
+	for _, s := range []string{"", "auto", "always", "never"} {
+		if _, err := ParseMarkdownMode(s); err != nil {
+			t.Errorf("ParseMarkdownMode(%q) failed: %v", s, err)
+		}
+	}
+	if _, err := ParseMarkdownMode("sometimes"); err == nil {
+		t.Error("expected error for unknown markdown mode")
+	}
+	if _, err := ParseHardBreakStyle("html"); err == nil {
+		t.Error("expected error for unknown hard break style")
+	}
+	if _, err := ParseCodeBlockPolicy("strip"); err == nil {
+		t.Error("expected error for unknown code block policy")
+	}
+}
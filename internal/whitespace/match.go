@@ -0,0 +1,174 @@
+package whitespace
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Pattern syntax for --exclude and --include
+//
+// Patterns are matched against slash-separated paths relative to the target
+// being processed, so the same pattern behaves identically whether the tool
+// is run on "." or on a subdirectory's parent. The rules follow .gitignore:
+//
+//   - A pattern without a slash, such as "*.tmp" or "bin", matches the file or
+//     directory name at any depth.
+//   - A pattern with a slash at the start or in the middle, such as "/build"
+//     or "docs/*.md", is anchored to the target root.
+//   - "*" and "?" never match "/"; "[abc]", "[a-z]" and "[^abc]" match one character;
+//     a backslash escapes the next character.
+//   - "**" as a whole segment matches zero or more directories: "**/testdata"
+//     matches at any depth, "docs/**" everything inside docs, and
+//     "a/**/b" matches a/b, a/x/b and a/x/y/b.
+//   - A trailing slash, as in "vendor/", only matches directories.
+//   - A leading "!" negates the pattern; the last matching pattern wins, so
+//     "*.md" followed by "!README.md" matches every Markdown file except
+//     READMEs. Use "\!" for a literal leading "!".
+//
+// A path is also matched when one of its parent directories is, so "vendor/"
+// applies to everything beneath vendor. As in git, a file inside a matched
+// directory cannot be re-included by a later negation.
+
+// pattern is one compiled exclude or include pattern
+type pattern struct {
+	raw      string
+	negate   bool
+	dirOnly  bool
+	segments []string
+}
+
+// patternSet is an ordered list of patterns where the last match wins
+type patternSet struct {
+	patterns []pattern
+}
+
+// compilePatterns parses and validates patterns. A nil set matches nothing.
+func compilePatterns(patterns []string) (*patternSet, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	ps := &patternSet{}
+	for _, raw := range patterns {
+		p := pattern{raw: raw}
+		s := filepath.ToSlash(raw)
+		if strings.HasPrefix(s, "!") {
+			p.negate = true
+			s = s[1:]
+		} else if strings.HasPrefix(s, `\!`) {
+			s = s[1:]
+		}
+		if strings.HasSuffix(s, "/") {
+			p.dirOnly = true
+			s = strings.TrimRight(s, "/")
+		}
+		if s == "" {
+			return nil, &PatternError{Pattern: raw, Reason: "empty pattern"}
+		}
+
+		// Only a slash before the end anchors the pattern to the root
+		if strings.HasPrefix(s, "/") {
+			s = strings.TrimLeft(s, "/")
+		} else if !strings.Contains(s, "/") {
+			s = "**/" + s
+		}
+
+		for _, seg := range strings.Split(s, "/") {
+			if seg == "" {
+				continue
+			}
+			if seg != "**" {
+				if _, err := path.Match(seg, ""); err != nil {
+					return nil, &PatternError{Pattern: raw, Reason: "malformed segment " + seg}
+				}
+			}
+			p.segments = append(p.segments, seg)
+		}
+		ps.patterns = append(ps.patterns, p)
+	}
+	return ps, nil
+}
+
+// PatternError reports an invalid exclude or include pattern
+type PatternError struct {
+	Pattern string
+	Reason  string
+}
+
+func (e *PatternError) Error() string {
+	return "invalid pattern " + e.Pattern + ": " + e.Reason
+}
+
+// match reports whether rel, a slash-separated path relative to the root,
+// is matched by the set itself (parents are not considered), along with the
+// deciding pattern.
+func (ps *patternSet) match(rel string, isDir bool) (bool, string) {
+	if ps == nil || rel == "" || rel == "." {
+		return false, ""
+	}
+	names := strings.Split(rel, "/")
+	matched, by := false, ""
+	for _, p := range ps.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if matchSegments(p.segments, names) {
+			matched, by = !p.negate, p.raw
+		}
+	}
+	return matched, by
+}
+
+// matchWithParents is like match, but also reports a match when any parent
+// directory of rel is matched. It is used for paths not reached by a walk
+// that would already have pruned matched directories.
+func (ps *patternSet) matchWithParents(rel string, isDir bool) (bool, string) {
+	if ps == nil {
+		return false, ""
+	}
+	for i := 0; i < len(rel); i++ {
+		if rel[i] == '/' {
+			if ok, by := ps.match(rel[:i], true); ok {
+				return true, by
+			}
+		}
+	}
+	return ps.match(rel, isDir)
+}
+
+// matchSegments matches pattern segments against path segments, with "**"
+// standing for zero or more whole segments
+func matchSegments(pat, names []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			pat = pat[1:]
+			if len(pat) == 0 {
+				// A trailing "**" matches everything inside, not the directory itself
+				return len(names) > 0
+			}
+			for i := 0; i <= len(names); i++ {
+				if matchSegments(pat, names[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(names) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], names[0]); !ok {
+			return false
+		}
+		pat, names = pat[1:], names[1:]
+	}
+	return len(names) == 0
+}
+
+// relativePath returns path relative to root in slash form, for pattern matching
+func relativePath(root, p string) string {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return filepath.ToSlash(p)
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,133 @@
+package whitespace
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPatternSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		matched  bool
+	}{
+		// Unanchored names match at any depth
+		{"name at root", []string{"*.tmp"}, "data.tmp", false, true},
+		{"name in subdir", []string{"*.tmp"}, "a/b/data.tmp", false, true},
+		{"name does not match extension prefix", []string{"*.tmp"}, "data.tmpl", false, false},
+		{"directory name at depth", []string{"bin"}, "src/bin", true, true},
+		{"star does not cross slash", []string{"a*b"}, "a/b", false, false},
+		{"question mark", []string{"file?.txt"}, "x/file1.txt", false, true},
+		{"character class", []string{"[abc].go"}, "b.go", false, true},
+		{"negated character class", []string{"[^abc].go"}, "b.go", false, false},
+
+		// Anchoring
+		{"middle slash anchors", []string{"docs/*.md"}, "docs/a.md", false, true},
+		{"middle slash anchors to root only", []string{"docs/*.md"}, "sub/docs/a.md", false, false},
+		{"anchored star does not descend", []string{"docs/*.md"}, "docs/x/a.md", false, false},
+		{"leading slash anchors", []string{"/build"}, "build", true, true},
+		{"leading slash does not match deeper", []string{"/build"}, "src/build", true, false},
+
+		// Double star
+		{"leading double star", []string{"**/testdata"}, "testdata", true, true},
+		{"leading double star at depth", []string{"**/testdata"}, "a/b/testdata", true, true},
+		{"trailing double star", []string{"docs/**"}, "docs/a/b.md", false, true},
+		{"trailing double star excludes the directory itself", []string{"docs/**"}, "docs", true, false},
+		{"middle double star zero dirs", []string{"a/**/b"}, "a/b", false, true},
+		{"middle double star several dirs", []string{"a/**/b"}, "a/x/y/b", false, true},
+		{"middle double star wrong root", []string{"a/**/b"}, "c/a/x/b", false, false},
+		{"double star with extension", []string{"**/*.pb.go"}, "api/v1/x.pb.go", false, true},
+
+		// Directory-only patterns
+		{"dir-only matches dir", []string{"vendor/"}, "vendor", true, true},
+		{"dir-only skips file", []string{"vendor/"}, "vendor", false, false},
+		{"dir-only nested", []string{"vendor/"}, "a/vendor", true, true},
+		{"anchored dir-only", []string{"/out/"}, "out", true, true},
+
+		// Negation, last match wins
+		{"negation re-includes", []string{"*.md", "!README.md"}, "README.md", false, false},
+		{"negation leaves others", []string{"*.md", "!README.md"}, "guide.md", false, true},
+		{"later pattern wins over negation", []string{"!keep.txt", "*.txt"}, "keep.txt", false, true},
+		{"escaped bang is literal", []string{`\!important`}, "!important", false, true},
+
+		// Normalization
+		{"root is never matched", []string{"*"}, ".", true, false},
+		{"backslash escapes", []string{`\*.txt`}, "*.txt", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps, err := compilePatterns(tt.patterns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := ps.match(tt.path, tt.isDir); got != tt.matched {
+				t.Errorf("patterns %q on %q (dir=%v): expected %v, got %v", tt.patterns, tt.path, tt.isDir, tt.matched, got)
+			}
+		})
+	}
+}
+
+func TestPatternSet_Parents(t *testing.T) {
+	ps, err := compilePatterns([]string{"vendor/", "docs/**", "!vendor/keep.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path    string
+		matched bool
+		by      string
+	}{
+		{"vendor/pkg/a.go", true, "vendor/"},
+		{"vendor/keep.go", true, "vendor/"}, // cannot re-include inside an excluded directory
+		{"docs/a/b.md", true, "docs/**"},
+		{"src/a.go", false, ""},
+	}
+	for _, tt := range tests {
+		matched, by := ps.matchWithParents(tt.path, false)
+		if matched != tt.matched || by != tt.by {
+			t.Errorf("%s: expected (%v, %q), got (%v, %q)", tt.path, tt.matched, tt.by, matched, by)
+		}
+	}
+}
+
+func TestPatternSet_Invalid(t *testing.T) {
+	for _, p := range []string{"[", "a/[b", "!", "/"} {
+		if _, err := compilePatterns([]string{p}); err == nil {
+			t.Errorf("expected error for pattern %q", p)
+		}
+	}
+}
+
+func TestExcludePatterns_RelativeToTarget(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"project/docs/a.md":     "a\n",
+		"project/docs/x/b.md":   "b\n",
+		"project/src/docs/c.md": "c\n",
+		"project/build/out.txt": "out\n",
+		"project/src/build/d":   "d\n",
+	})
+	opts := Options{ExcludePatterns: []string{"docs/*.md", "/build/"}}
+
+	// The same patterns apply whether the walk starts at the target or is
+	// given a path containing it
+	for _, target := range []string{filepath.Join(tmpDir, "project"), filepath.Join(tmpDir, "project") + string(filepath.Separator)} {
+		mock := &mockProcessor{}
+		if err := processTarget(target, opts, mock.process); err != nil {
+			t.Fatal(err)
+		}
+		got := relPaths(t, filepath.Join(tmpDir, "project"), mock.processedFiles)
+		expected := []string{"docs/x/b.md", "src/build/d", "src/docs/c.md"}
+		if len(got) != len(expected) {
+			t.Fatalf("target %s: expected %v, got %v", target, expected, got)
+		}
+		for i := range got {
+			if got[i] != expected[i] {
+				t.Fatalf("target %s: expected %v, got %v", target, expected, got)
+			}
+		}
+	}
+}
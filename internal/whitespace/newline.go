@@ -2,33 +2,51 @@ package whitespace
 
 import (
 	"bytes"
-	"io"
-	"os"
+	"context"
 )
 
-// ensureSingleNewline rewrites the file so it ends with exactly one newline.
-func ensureSingleNewline(path string) error {
-	input, err := os.ReadFile(path)
-	if err != nil {
-		return err
+// fixNewline returns input ending with exactly one newline.
+func fixNewline(input []byte) []byte {
+	trimmed := bytes.TrimRight(input, "\r\n")
+	if parseSuppressions(input).suppressed(lastLine(trimmed), RuleNewline) {
+		return input
 	}
-	input = bytes.TrimRight(input, "\r\n")
-	input = append(input, '\n')
-	info, err := os.Stat(path)
-	if err != nil {
-		return err
-	}
-	tmp := path + ".tmp"
-	if err := os.WriteFile(tmp, input, info.Mode()); err != nil {
-		return err
+	// Force a copy so input is left intact for backups
+	return append(trimmed[:len(trimmed):len(trimmed)], '\n')
+}
+
+// checkNewline reports content that fixNewline would change, on its last line
+func checkNewline(input []byte) []Finding {
+	trimmed := bytes.TrimRight(input, "\r\n")
+	tail := input[len(trimmed):]
+	if len(trimmed) > 0 && string(tail) == "\n" {
+		return nil
 	}
-	f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, info.Mode())
-	if err != nil {
-		return err
+	message := "file does not end with exactly one newline"
+	switch {
+	case len(tail) == 0:
+		message = "missing final newline"
+	case bytes.Count(tail, []byte("\n")) > 1:
+		message = "extra blank lines at end of file"
 	}
-	defer f.Close()
-	_, err = io.Copy(f, bytes.NewReader(input))
-	os.Remove(tmp)
+	last := trimmed[bytes.LastIndexByte(trimmed, '\n')+1:]
+	return parseSuppressions(input).filter([]Finding{{
+		Line:    lastLine(trimmed),
+		Column:  endColumn(string(last)),
+		Rule:    RuleNewline,
+		Message: message,
+		Text:    string(last),
+	}})
+}
+
+// lastLine returns the 1-based number of the last line of content trimmed of trailing newlines
+func lastLine(trimmed []byte) int {
+	return bytes.Count(trimmed, []byte("\n")) + 1
+}
+
+// ensureSingleNewline rewrites the file so it ends with exactly one newline.
+func ensureSingleNewline(path string) error {
+	_, err := rewriteFile(path, fixNewline, &Options{})
 	return err
 }
 
@@ -39,5 +57,24 @@ func ProcessNewline(target string) error {
 
 // ProcessNewlineWithOptions processes a file or directory with the given options.
 func ProcessNewlineWithOptions(target string, opts Options) error {
-	return processTarget(target, opts, ensureSingleNewline)
+	return processTarget(target, opts, func(path string) error {
+		_, err := rewriteFile(path, fixNewline, &opts)
+		return err
+	})
+}
+
+// CheckNewlineWithOptions reports files under target that do not end with
+// exactly one newline, without modifying them.
+func CheckNewlineWithOptions(target string, opts Options) ([]Finding, error) {
+	return checkTarget(target, opts, func(string) checkFunc { return checkNewline })
+}
+
+// WatchNewlineWithOptions processes target like ProcessNewlineWithOptions,
+// then keeps fixing files under it as they are created or saved until ctx
+// is done.
+func WatchNewlineWithOptions(ctx context.Context, target string, opts Options) error {
+	return watchTarget(ctx, target, opts, func(path string) error {
+		_, err := rewriteFile(path, fixNewline, &opts)
+		return err
+	})
 }
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package whitespace
+
+import "io/fs"
+
+// linkCount returns 1; link counts are not exposed through fs.FileInfo on this platform
+func linkCount(info fs.FileInfo) uint64 {
+	return 1
+}
@@ -2,82 +2,298 @@ package whitespace
 
 import (
 	"bufio"
+	"bytes"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"unicode/utf8"
 )
 
-// LooksText reports whether the file appears to be text based on a small prefix.
-// - Reads up to maxBytes (default 4096).
-// - Returns false for NUL bytes or high ratio of non-text bytes.
-// - Directories are false. Symlinks are followed once.
+// sampleBytes is how much of a file is inspected to classify it
+const sampleBytes = 8192
+
+// TextDetect selects how files are classified as text or binary
+type TextDetect string
+
+const (
+	// TextDetectHeuristic uses magic numbers, BOMs and a byte-level heuristic (default)
+	TextDetectHeuristic TextDetect = "heuristic"
+	// TextDetectExtension trusts well-known file extensions, falling back to the heuristic
+	TextDetectExtension TextDetect = "extension"
+	// TextDetectStrict only accepts valid UTF-8 (or BOM-marked UTF-16/32) without control characters
+	TextDetectStrict TextDetect = "strict"
+)
+
+// ParseTextDetect validates a --text-detect value; the empty string selects TextDetectHeuristic
+func ParseTextDetect(s string) (TextDetect, error) {
+	switch m := TextDetect(s); m {
+	case "":
+		return TextDetectHeuristic, nil
+	case TextDetectHeuristic, TextDetectExtension, TextDetectStrict:
+		return m, nil
+	}
+	return "", fmt.Errorf("invalid text detection mode %q (want heuristic, extension or strict)", s)
+}
+
+// Encoding identifies the character encoding of a text file
+type Encoding string
+
+const (
+	EncodingUTF8    Encoding = "utf-8" // also ASCII, with or without BOM
+	EncodingUTF16LE Encoding = "utf-16le"
+	EncodingUTF16BE Encoding = "utf-16be"
+	EncodingUTF32LE Encoding = "utf-32le"
+	EncodingUTF32BE Encoding = "utf-32be"
+)
+
+// byteOrderMarks maps BOMs to encodings; UTF-32LE must precede UTF-16LE as it shares a prefix
+var byteOrderMarks = []struct {
+	bom []byte
+	enc Encoding
+}{
+	{[]byte{0xFF, 0xFE, 0x00, 0x00}, EncodingUTF32LE},
+	{[]byte{0x00, 0x00, 0xFE, 0xFF}, EncodingUTF32BE},
+	{[]byte{0xFF, 0xFE}, EncodingUTF16LE},
+	{[]byte{0xFE, 0xFF}, EncodingUTF16BE},
+	{[]byte{0xEF, 0xBB, 0xBF}, EncodingUTF8},
+}
+
+// bomEncoding returns the encoding announced by a BOM at the start of buf, if any
+func bomEncoding(buf []byte) (Encoding, bool) {
+	for _, b := range byteOrderMarks {
+		if bytes.HasPrefix(buf, b.bom) {
+			return b.enc, true
+		}
+	}
+	return "", false
+}
+
+// binaryMagic lists signatures of common binary formats. Several of them
+// (PDF in particular) start with readable text that fools the heuristic.
+var binaryMagic = []struct {
+	format string
+	magic  []byte
+}{
+	{"PNG", []byte("\x89PNG\r\n\x1a\n")},
+	{"JPEG", []byte{0xFF, 0xD8, 0xFF}},
+	{"GIF", []byte("GIF87a")},
+	{"GIF", []byte("GIF89a")},
+	{"ZIP", []byte("PK\x03\x04")},
+	{"ZIP", []byte("PK\x05\x06")},
+	{"ZIP", []byte("PK\x07\x08")},
+	{"PDF", []byte("%PDF-")},
+	{"ELF", []byte("\x7fELF")},
+	{"Mach-O", []byte{0xCF, 0xFA, 0xED, 0xFE}},
+	{"Mach-O", []byte{0xFE, 0xED, 0xFA, 0xCF}},
+	{"gzip", []byte{0x1F, 0x8B}},
+	{"xz", []byte("\xFD7zXZ\x00")},
+	{"zstd", []byte{0x28, 0xB5, 0x2F, 0xFD}},
+	{"7z", []byte("7z\xBC\xAF\x27\x1C")},
+	{"SQLite", []byte("SQLite format 3\x00")},
+	{"WebAssembly", []byte("\x00asm")},
+	{"Java class", []byte{0xCA, 0xFE, 0xBA, 0xBE}},
+}
+
+// magicFormat returns the binary format whose signature starts buf, if any
+func magicFormat(buf []byte) (string, bool) {
+	for _, m := range binaryMagic {
+		if bytes.HasPrefix(buf, m.magic) {
+			return m.format, true
+		}
+	}
+	return "", false
+}
+
+// textExtensions and binaryExtensions drive TextDetectExtension
+var textExtensions = map[string]bool{
+	".txt": true, ".md": true, ".markdown": true, ".rst": true, ".adoc": true,
+	".go": true, ".mod": true, ".c": true, ".h": true, ".cc": true, ".cpp": true, ".hpp": true,
+	".rs": true, ".py": true, ".rb": true, ".pl": true, ".php": true, ".java": true, ".kt": true,
+	".js": true, ".mjs": true, ".cjs": true, ".ts": true, ".tsx": true, ".jsx": true,
+	".css": true, ".scss": true, ".html": true, ".htm": true, ".xml": true, ".svg": true,
+	".json": true, ".yaml": true, ".yml": true, ".toml": true, ".ini": true, ".cfg": true, ".conf": true,
+	".sh": true, ".bash": true, ".zsh": true, ".fish": true, ".ps1": true, ".bat": true, ".cmd": true,
+	".sql": true, ".proto": true, ".tf": true, ".csv": true, ".tsv": true, ".reg": true, ".rc": true,
+}
+
+var binaryExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true, ".ico": true, ".webp": true,
+	".pdf": true, ".zip": true, ".gz": true, ".tgz": true, ".bz2": true, ".xz": true, ".zst": true, ".7z": true,
+	".jar": true, ".class": true, ".so": true, ".dylib": true, ".dll": true, ".exe": true, ".o": true, ".a": true,
+	".wasm": true, ".db": true, ".sqlite": true, ".woff": true, ".woff2": true, ".ttf": true, ".otf": true,
+	".mp3": true, ".mp4": true, ".mov": true, ".wav": true, ".ogg": true,
+}
+
+// LooksText reports whether the file appears to be text based on a small prefix,
+// using the default heuristic detection.
+// - Reads up to 8 KiB.
+// - Returns false for known binary signatures (PNG, ZIP, PDF, ELF, gzip, SQLite, ...).
+// - Returns true for UTF-16/32 content marked by a byte order mark, and for BOM-less UTF-16.
+// - Otherwise returns false for NUL bytes or high ratio of non-text bytes.
+// - Directories and other non-regular files are false.
+// - Symlinks resolve relative to the link's directory; dangling links and loops are false.
 func LooksText(path string) (bool, error) {
-	const maxBytes = 4096
+	isText, _, err := DetectText(path, TextDetect(""), "")
+	return isText, err
+}
+
+// DetectText classifies the file at path with the given mode and, for text
+// files, reports the encoding found. legacy, if set, is the single-byte
+// encoding assumed for text that is not valid UTF-8.
+func DetectText(path string, mode TextDetect, legacy Encoding) (bool, Encoding, error) {
+	in, err := inspect(path, mode, legacy)
+	return in.text, in.enc, err
+}
+
+// inspection is what was learned about a file from its first bytes
+type inspection struct {
+	text      bool
+	enc       Encoding
+	generated bool // text carrying a "Code generated ... DO NOT EDIT." marker
+}
 
+// inspect samples the start of the file at path and classifies it
+func inspect(path string, mode TextDetect, legacy Encoding) (inspection, error) {
 	fi, err := os.Lstat(path)
 	if err != nil {
-		return false, err
-	}
-	if fi.IsDir() {
-		return false, nil
+		return inspection{}, err
 	}
 	if fi.Mode()&os.ModeSymlink != 0 {
-		t, err := os.Readlink(path)
-		if err != nil {
-			return false, err
+		real, ok, err := resolveSymlink(path)
+		if err != nil || !ok {
+			return inspection{}, err
 		}
-		path = t
+		if fi, err = os.Stat(real); err != nil {
+			return inspection{}, err
+		}
+		path = real
+	}
+	if !fi.Mode().IsRegular() {
+		return inspection{}, nil
 	}
 
 	f, err := os.Open(path)
 	if err != nil {
-		return false, err
+		return inspection{}, err
 	}
 	defer f.Close()
 
-	r := bufio.NewReader(f)
-	buf, err := r.Peek(maxBytes)
+	r := bufio.NewReaderSize(f, sampleBytes)
+	buf, err := r.Peek(sampleBytes)
 	if err != nil && err != bufio.ErrBufferFull && err != io.EOF {
-		// For short files, EOF is expected; ErrBufferFull means we read maxBytes.
-		return false, err
+		// For short files, EOF is expected; ErrBufferFull means we read sampleBytes.
+		return inspection{}, err
+	}
+
+	return inspectSample(buf, filepath.Base(path), mode, legacy, len(buf) == sampleBytes), nil
+}
+
+// inspectSample classifies sample, the start of a file named name
+func inspectSample(sample []byte, name string, mode TextDetect, legacy Encoding, truncated bool) inspection {
+	var in inspection
+	in.text, in.enc = classify(sample, name, mode, legacy, truncated)
+	in.generated = in.text && in.enc == EncodingUTF8 && isGenerated(sample)
+	return in
+}
+
+// classify decides whether sample (the start of a file named name) is text.
+// truncated reports that the sample is a prefix of a larger file.
+func classify(sample []byte, name string, mode TextDetect, legacy Encoding, truncated bool) (bool, Encoding) {
+	if mode == TextDetectExtension {
+		ext := strings.ToLower(filepath.Ext(name))
+		if binaryExtensions[ext] {
+			return false, ""
+		}
+		if textExtensions[ext] {
+			return true, sniffEncoding(sample, legacy)
+		}
+		// Unknown extension: fall back to the heuristic
 	}
-	if len(buf) == 0 {
+
+	if len(sample) == 0 {
 		// Empty file: treat as text (safe to add newline/trim).
-		return true, nil
+		return true, EncodingUTF8
+	}
+	if _, ok := magicFormat(sample); ok {
+		return false, ""
+	}
+	if enc, ok := bomEncoding(sample); ok && enc != EncodingUTF8 {
+		return true, enc
+	}
+	if enc, ok := sniffUTF16(sample); ok {
+		text, err := decodeText(sample[:len(sample)&^1], enc)
+		if err == nil && countNonText(text, truncated)*10 <= len(text) {
+			return true, enc
+		}
 	}
 
 	// Immediate binary indicator: any NUL byte.
-	for _, b := range buf {
-		if b == 0x00 {
-			return false, nil
-		}
+	if bytes.IndexByte(sample, 0x00) >= 0 {
+		return false, ""
 	}
 
-	// Count suspicious bytes: invalid UTF-8 or disallowed controls.
+	enc := EncodingUTF8
+	if legacy != "" && !validUTF8(sample, truncated) {
+		// Every high byte is a character in a single-byte encoding
+		enc = legacy
+		sample, _ = decodeText(sample, legacy)
+	}
+
+	nonText := countNonText(sample, truncated)
+	if mode == TextDetectStrict && nonText > 0 {
+		return false, ""
+	}
+	// If more than ~30% of sampled bytes are non-text, treat as binary.
+	if float64(nonText) > 0.30*float64(len(sample)) {
+		return false, ""
+	}
+	return true, enc
+}
+
+// countNonText counts suspicious bytes: invalid UTF-8 or disallowed controls.
+// A multi-byte sequence cut off by the end of a truncated sample is not counted.
+func countNonText(buf []byte, truncated bool) int {
 	var nonText int
 	for i := 0; i < len(buf); {
 		b := buf[i]
 		if b < 0x80 {
 			switch b {
-			case '\n', '\r', '\t':
+			case '\n', '\r', '\t', '\f':
 				// common controls allowed
 			default:
-				if b < 0x20 && b != '\n' && b != '\r' && b != '\t' {
+				if b < 0x20 || b == 0x7F {
 					nonText++
 				}
 			}
 			i++
 			continue
 		}
-		_, size := utf8.DecodeRune(buf[i:])
-		if size == 1 && b >= 0x80 {
+		r, size := utf8.DecodeRune(buf[i:])
+		if r == utf8.RuneError && size == 1 {
+			if truncated && !utf8.FullRune(buf[i:]) {
+				break
+			}
 			nonText++
-			i++
-		} else {
-			i += size
 		}
+		i += size
 	}
+	return nonText
+}
 
-	// If more than ~30% of sampled bytes are non-text, treat as binary.
-	return float64(nonText) <= 0.30*float64(len(buf)), nil
+// validUTF8 reports whether buf is valid UTF-8, ignoring a multi-byte
+// sequence cut off by the end of a truncated sample
+func validUTF8(buf []byte, truncated bool) bool {
+	if truncated {
+		for i := len(buf) - 1; i >= 0 && i >= len(buf)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(buf[i]) {
+				if !utf8.FullRune(buf[i:]) {
+					buf = buf[:i]
+				}
+				break
+			}
+		}
+	}
+	return utf8.Valid(buf)
 }
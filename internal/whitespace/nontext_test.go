@@ -0,0 +1,148 @@
+package whitespace
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// utf16le encodes ASCII s as UTF-16LE with a BOM
+func utf16le(s string) []byte {
+	out := []byte{0xFF, 0xFE}
+	for _, c := range []byte(s) {
+		out = append(out, c, 0x00)
+	}
+	return out
+}
+
+// utf16be encodes ASCII s as UTF-16BE with a BOM
+func utf16be(s string) []byte {
+	out := []byte{0xFE, 0xFF}
+	for _, c := range []byte(s) {
+		out = append(out, 0x00, c)
+	}
+	return out
+}
+
+func TestDetectText(t *testing.T) {
+	// Expected results per mode: heuristic, extension, strict
+	type result struct {
+		text bool
+		enc  Encoding
+	}
+	binary := result{false, ""}
+	utf8Text := result{true, EncodingUTF8}
+
+	tests := []struct {
+		name      string
+		file      string
+		content   []byte
+		heuristic result
+		extension result
+		strict    result
+	}{
+		{"empty", "empty.txt", nil, utf8Text, utf8Text, utf8Text},
+		{"ascii", "plain.txt", []byte("hello world\n"), utf8Text, utf8Text, utf8Text},
+		{"utf-8", "unicode.md", []byte("héllo wörld ✓\n"), utf8Text, utf8Text, utf8Text},
+		{"utf-8 with BOM", "bom.txt", []byte("\xEF\xBB\xBFhello\n"), utf8Text, result{true, EncodingUTF8}, utf8Text},
+		{"utf-16le with BOM", "res.rc", utf16le("hello\r\n"), result{true, EncodingUTF16LE}, result{true, EncodingUTF16LE}, result{true, EncodingUTF16LE}},
+		{"utf-16be with BOM", "data", utf16be("hello\n"), result{true, EncodingUTF16BE}, result{true, EncodingUTF16BE}, result{true, EncodingUTF16BE}},
+		{"utf-32le with BOM", "wide", []byte("\xFF\xFE\x00\x00h\x00\x00\x00i\x00\x00\x00"), result{true, EncodingUTF32LE}, result{true, EncodingUTF32LE}, result{true, EncodingUTF32LE}},
+		{"utf-16le without BOM", "nobom", []byte("h\x00i\x00\n\x00"), result{true, EncodingUTF16LE}, result{true, EncodingUTF16LE}, result{true, EncodingUTF16LE}},
+		{"utf-16be without BOM", "nobom-be", []byte("\x00h\x00i\x00\n"), result{true, EncodingUTF16BE}, result{true, EncodingUTF16BE}, result{true, EncodingUTF16BE}},
+		{"int16 table", "table", []byte("\x01\x00\x02\x00\x03\x00\x04\x00"), binary, binary, binary},
+		{"PNG", "image.png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), binary, binary, binary},
+		{"PNG renamed to .txt", "image.txt", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), binary, utf8Text, binary},
+		{"ZIP", "archive", []byte("PK\x03\x04\x14\x00mimetypeapplication/epub+zip"), binary, binary, binary},
+		{"PDF with text header", "doc.pdf", []byte("%PDF-1.7\n%comment\n1 0 obj\n<< /Type /Catalog >>\nendobj\n"), binary, binary, binary},
+		{"ELF", "prog", []byte("\x7fELF\x02\x01\x01"), binary, binary, binary},
+		{"gzip", "log.gz", []byte("\x1f\x8b\x08\x08name.txt"), binary, binary, binary},
+		{"SQLite", "app.db", []byte("SQLite format 3\x00\x10\x00"), binary, binary, binary},
+		{"NUL bytes", "blob", []byte("abc\x00def"), binary, binary, binary},
+		{"a few control characters", "ctrl.log", []byte("plain text with a bell\a in it\n"), utf8Text, utf8Text, binary},
+		{"invalid UTF-8 (Latin-1)", "latin1.txt", []byte("caf\xe9 cr\xe8me\n"), utf8Text, utf8Text, binary},
+		{"mostly control bytes", "noise", bytes.Repeat([]byte{0x01, 0x02, 'a'}, 100), binary, binary, binary},
+		{"text with unknown extension", "notes.xyz", []byte("notes\n"), utf8Text, utf8Text, utf8Text},
+	}
+
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(path, tt.content, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(path)
+
+			for mode, want := range map[TextDetect]result{
+				TextDetectHeuristic: tt.heuristic,
+				TextDetectExtension: tt.extension,
+				TextDetectStrict:    tt.strict,
+			} {
+				isText, enc, err := DetectText(path, mode, "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if isText != want.text || enc != want.enc {
+					t.Errorf("%s: expected (%v, %q), got (%v, %q)", mode, want.text, want.enc, isText, enc)
+				}
+			}
+
+			isText, err := LooksText(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if isText != tt.heuristic.text {
+				t.Errorf("LooksText: expected %v, got %v", tt.heuristic.text, isText)
+			}
+		})
+	}
+}
+
+func TestDetectText_TruncatedRune(t *testing.T) {
+	// A multi-byte rune straddling the end of the sample must not count against strict mode
+	content := append(bytes.Repeat([]byte("a"), sampleBytes-1), "é and more\n"...)
+	path := filepath.Join(t.TempDir(), "long.txt")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	isText, _, err := DetectText(path, TextDetectStrict, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isText {
+		t.Error("expected strict detection to accept a rune cut by the sample boundary")
+	}
+}
+
+func TestDetectText_LegacyEncoding(t *testing.T) {
+	// Mostly accented Latin-1: too many invalid UTF-8 bytes for the heuristic
+	content := []byte("\xe9\xe8\xe0\xe7\xf4 \xe9t\xe9\n")
+	path := filepath.Join(t.TempDir(), "legacy")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if isText, _, _ := DetectText(path, TextDetectStrict, ""); isText {
+		t.Error("expected Latin-1 to be rejected without a legacy encoding")
+	}
+	isText, enc, err := DetectText(path, TextDetectStrict, EncodingLatin1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isText || enc != EncodingLatin1 {
+		t.Errorf("expected (true, %q), got (%v, %q)", EncodingLatin1, isText, enc)
+	}
+}
+
+func TestParseTextDetect(t *testing.T) {
+	for _, s := range []string{"", "heuristic", "extension", "strict"} {
+		if _, err := ParseTextDetect(s); err != nil {
+			t.Errorf("ParseTextDetect(%q) failed: %v", s, err)
+		}
+	}
+	if _, err := ParseTextDetect("magic"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
@@ -0,0 +1,311 @@
+package whitespace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Format selects how the findings of a check are written
+type Format string
+
+const (
+	// FormatText writes each finding with its line, for people to read (default)
+	FormatText Format = "text"
+	// FormatCheckstyle writes Checkstyle XML, with an <error> per finding
+	FormatCheckstyle Format = "checkstyle"
+	// FormatJUnit writes JUnit XML, with a test case per file
+	FormatJUnit Format = "junit"
+	// FormatGitHub writes GitHub Actions workflow commands, annotating each finding
+	FormatGitHub Format = "github"
+	// FormatGitLab writes a GitLab Code Quality report
+	FormatGitLab Format = "gitlab"
+)
+
+// reporters maps each format to its writer
+var reporters = map[Format]func(w io.Writer, r Report) error{
+	FormatText:       writeText,
+	FormatCheckstyle: writeCheckstyle,
+	FormatJUnit:      writeJUnit,
+	FormatGitHub:     writeGitHub,
+	FormatGitLab:     writeGitLab,
+}
+
+// ParseFormat validates a --format value; the empty string selects FormatText
+func ParseFormat(s string) (Format, error) {
+	f := Format(s)
+	if s == "" {
+		return FormatText, nil
+	}
+	if _, ok := reporters[f]; ok {
+		return f, nil
+	}
+	return "", fmt.Errorf("invalid format %q (want text, checkstyle, junit, github or gitlab)", s)
+}
+
+// Report is the outcome of checking files for one rule
+type Report struct {
+	Rule     string    // Rule checked
+	Files    []string  // Files checked, in order; files with findings are added if missing
+	Findings []Finding // Problems found, in file order
+	Color    bool      // Highlight text output with ANSI escape sequences
+}
+
+// WriteReport writes r to w in format
+func WriteReport(w io.Writer, format Format, r Report) error {
+	write, ok := reporters[format]
+	if !ok {
+		return fmt.Errorf("invalid format %q", format)
+	}
+	return write(w, r)
+}
+
+// byFile returns the files of r in order with the findings for each
+func (r Report) byFile() ([]string, map[string][]Finding) {
+	files := append([]string(nil), r.Files...)
+	found := make(map[string][]Finding)
+	for _, f := range r.Findings {
+		found[f.Path] = append(found[f.Path], f)
+	}
+	listed := make(map[string]bool, len(files))
+	for _, path := range files {
+		listed[path] = true
+	}
+	for _, f := range r.Findings {
+		if !listed[f.Path] {
+			listed[f.Path] = true
+			files = append(files, f.Path)
+		}
+	}
+	return files, found
+}
+
+// ANSI escape sequences used by text output
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiRed   = "\x1b[31m"
+	ansiCyan  = "\x1b[36m"
+)
+
+// writeText writes each finding followed by its line, indented, with the
+// whitespace at fault made visible
+func writeText(w io.Writer, r Report) error {
+	paint := func(code, s string) string {
+		if !r.Color || s == "" {
+			return s
+		}
+		return code + s + ansiReset
+	}
+	for _, f := range r.Findings {
+		head, tail := splitColumn(f.Text, f.Column)
+		_, err := fmt.Fprintf(w, "%s %s %s\n",
+			paint(ansiBold, fmt.Sprintf("%s:%d:%d:", f.Path, f.Line, f.Column)), paint(ansiCyan, f.Rule+":"), f.Message)
+		if err != nil {
+			return err
+		}
+		if f.Text == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "    %s%s\n", head, paint(ansiRed, visibleWhitespace.Replace(tail))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// visibleWhitespace shows spaces as middle dots and tabs as arrows
+var visibleWhitespace = strings.NewReplacer(" ", "\u00b7", "\t", "\u2192")
+
+// splitColumn splits line before the 1-based character column
+func splitColumn(line string, column int) (string, string) {
+	n := 0
+	for i := range line {
+		if n++; n == column {
+			return line[:i], line[i:]
+		}
+	}
+	return line, ""
+}
+
+// writeXML writes v as an indented XML document
+func writeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type checkstyleReport struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Column   int    `xml:"column,attr,omitempty"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+// writeCheckstyle lists every checked file, as Checkstyle does, so clean
+// files show up as passing
+func writeCheckstyle(w io.Writer, r Report) error {
+	files, found := r.byFile()
+	report := checkstyleReport{Version: "4.3"}
+	for _, path := range files {
+		file := checkstyleFile{Name: path}
+		for _, f := range found[path] {
+			file.Errors = append(file.Errors, checkstyleError{
+				Line:     f.Line,
+				Column:   f.Column,
+				Severity: "error",
+				Message:  f.Message,
+				Source:   "whitespace." + f.Rule,
+			})
+		}
+		report.Files = append(report.Files, file)
+	}
+	return writeXML(w, report)
+}
+
+type junitReport struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes a test suite for the rule, with a test case per file
+// that fails with the file's findings
+func writeJUnit(w io.Writer, r Report) error {
+	files, found := r.byFile()
+	suite := junitSuite{Name: r.Rule, Tests: len(files)}
+	for _, path := range files {
+		c := junitCase{Name: path, ClassName: r.Rule}
+		if findings := found[path]; len(findings) > 0 {
+			var details strings.Builder
+			for _, f := range findings {
+				fmt.Fprintln(&details, f)
+			}
+			c.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d %s problem(s)", len(findings), r.Rule),
+				Type:    r.Rule,
+				Text:    details.String(),
+			}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	return writeXML(w, junitReport{
+		Name:     "whitespace",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Suites:   []junitSuite{suite},
+	})
+}
+
+// githubEscaper escapes workflow command data; githubPropertyEscaper also
+// escapes the separators of command properties
+var (
+	githubEscaper         = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	githubPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+// writeGitHub writes an ::error workflow command per finding, which GitHub
+// Actions shows as an annotation on the line. Paths are relative to the
+// working directory, normally the root of the checkout.
+func writeGitHub(w io.Writer, r Report) error {
+	for _, f := range r.Findings {
+		_, err := fmt.Fprintf(w, "::error file=%s,line=%d,col=%d,title=%s::%s\n",
+			githubPropertyEscaper.Replace(baselinePath(f.Path)), f.Line, f.Column,
+			githubPropertyEscaper.Replace("whitespace "+f.Rule), githubEscaper.Replace(f.Message))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gitlabIssue is an entry of a GitLab Code Quality report
+type gitlabIssue struct {
+	Description string         `json:"description"`
+	CheckName   string         `json:"check_name"`
+	Fingerprint string         `json:"fingerprint"`
+	Severity    string         `json:"severity"`
+	Location    gitlabLocation `json:"location"`
+}
+
+type gitlabLocation struct {
+	Path  string `json:"path"`
+	Lines struct {
+		Begin int `json:"begin"`
+	} `json:"lines"`
+}
+
+// writeGitLab writes a Code Quality report. Fingerprints are derived like
+// baseline keys, from the path, rule and offending line rather than its
+// number, so GitLab tracks a problem as the same one while code around it
+// changes; identical lines are told apart by their order.
+func writeGitLab(w io.Writer, r Report) error {
+	issues := []gitlabIssue{}
+	seen := make(map[baselineKey]int)
+	for _, f := range r.Findings {
+		k := newBaselineKey(f)
+		sum := sha256.Sum256(fmt.Appendf(nil, "%s\x00%s\x00%s\x00%d", k.path, k.rule, k.hash, seen[k]))
+		seen[k]++
+		issue := gitlabIssue{
+			Description: f.Message,
+			CheckName:   "whitespace." + f.Rule,
+			Fingerprint: hex.EncodeToString(sum[:]),
+			Severity:    "minor",
+			Location:    gitlabLocation{Path: k.path},
+		}
+		issue.Location.Lines.Begin = f.Line
+		issues = append(issues, issue)
+	}
+	data, err := json.MarshalIndent(issues, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,209 @@
+package whitespace
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"testing"
+)
+
+func testReport() Report {
+	return Report{
+		Rule:  RuleTrailingspace,
+		Files: []string{"a.go", "clean.go", "b & c.md"},
+		Findings: []Finding{
+			{Path: "a.go", Line: 3, Column: 7, Rule: RuleTrailingspace, Message: "trailing whitespace", Text: "x := 1 \t"},
+			{Path: "a.go", Line: 9, Column: 1, Rule: RuleTrailingspace, Message: "trailing whitespace", Text: "  "},
+			{Path: "b & c.md", Line: 1, Column: 2, Rule: RuleTrailingspace, Message: "trailing whitespace", Text: "é "},
+		},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for s, want := range map[string]Format{
+		"": FormatText, "text": FormatText, "checkstyle": FormatCheckstyle, "junit": FormatJUnit,
+		"github": FormatGitHub, "gitlab": FormatGitLab,
+	} {
+		if got, err := ParseFormat(s); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v; expected %q", s, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{FormatText, `a.go:3:7: trailingspace: trailing whitespace
+    x := 1·→
+a.go:9:1: trailingspace: trailing whitespace
+    ··
+b & c.md:1:2: trailingspace: trailing whitespace
+    é·
+`},
+		{FormatCheckstyle, `<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="4.3">
+  <file name="a.go">
+    <error line="3" column="7" severity="error" message="trailing whitespace" source="whitespace.trailingspace"></error>
+    <error line="9" column="1" severity="error" message="trailing whitespace" source="whitespace.trailingspace"></error>
+  </file>
+  <file name="clean.go"></file>
+  <file name="b &amp; c.md">
+    <error line="1" column="2" severity="error" message="trailing whitespace" source="whitespace.trailingspace"></error>
+  </file>
+</checkstyle>
+`},
+		{FormatJUnit, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="whitespace" tests="3" failures="2">
+  <testsuite name="trailingspace" tests="3" failures="2" errors="0">
+    <testcase name="a.go" classname="trailingspace">
+      <failure message="2 trailingspace problem(s)" type="trailingspace">a.go:3:7: trailingspace: trailing whitespace&#xA;a.go:9:1: trailingspace: trailing whitespace&#xA;</failure>
+    </testcase>
+    <testcase name="clean.go" classname="trailingspace"></testcase>
+    <testcase name="b &amp; c.md" classname="trailingspace">
+      <failure message="1 trailingspace problem(s)" type="trailingspace">b &amp; c.md:1:2: trailingspace: trailing whitespace&#xA;</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`},
+		{FormatGitHub, `::error file=a.go,line=3,col=7,title=whitespace trailingspace::trailing whitespace
+::error file=a.go,line=9,col=1,title=whitespace trailingspace::trailing whitespace
+::error file=b & c.md,line=1,col=2,title=whitespace trailingspace::trailing whitespace
+`},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteReport(&buf, tt.format, testReport()); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("expected\n%s\ngot\n%s", tt.want, buf.String())
+			}
+			if tt.format == FormatCheckstyle || tt.format == FormatJUnit {
+				dec := xml.NewDecoder(&buf)
+				for {
+					_, err := dec.Token()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						t.Fatalf("invalid XML: %v", err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWriteReport_TextColor(t *testing.T) {
+	r := Report{Color: true, Findings: []Finding{
+		{Path: "a.go", Line: 2, Column: 4, Rule: RuleTrailingspace, Message: "trailing whitespace", Text: "foo\t "},
+		{Path: "a.go", Line: 5, Column: 1, Rule: RuleNewline, Message: "missing final newline"},
+	}}
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, FormatText, r); err != nil {
+		t.Fatal(err)
+	}
+	want := "\x1b[1ma.go:2:4:\x1b[0m \x1b[36mtrailingspace:\x1b[0m trailing whitespace\n" +
+		"    foo\x1b[31m→·\x1b[0m\n" +
+		"\x1b[1ma.go:5:1:\x1b[0m \x1b[36mnewline:\x1b[0m missing final newline\n"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestSplitColumn(t *testing.T) {
+	tests := []struct {
+		line       string
+		column     int
+		head, tail string
+	}{
+		{"ab  ", 3, "ab", "  "},
+		{"é\t", 2, "é", "\t"},
+		{"  ", 1, "", "  "},
+		{"ab", 3, "ab", ""},
+		{"", 1, "", ""},
+	}
+	for _, tt := range tests {
+		if head, tail := splitColumn(tt.line, tt.column); head != tt.head || tail != tt.tail {
+			t.Errorf("splitColumn(%q, %d) = %q, %q; expected %q, %q", tt.line, tt.column, head, tail, tt.head, tt.tail)
+		}
+	}
+}
+
+func TestWriteReport_FindingsWithoutFiles(t *testing.T) {
+	r := testReport()
+	r.Files = nil
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, FormatJUnit, r); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`tests="2" failures="2"`)) {
+		t.Errorf("expected the files with findings to be listed, got\n%s", buf.String())
+	}
+}
+
+func TestWriteReport_GitHubEscaping(t *testing.T) {
+	r := Report{Findings: []Finding{{Path: "dir/a,b:c%.txt", Line: 1, Column: 1, Rule: RuleNewline, Message: "50% done\nnext"}}}
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, FormatGitHub, r); err != nil {
+		t.Fatal(err)
+	}
+	want := "::error file=dir/a%2Cb%3Ac%25.txt,line=1,col=1,title=whitespace newline::50%25 done%0Anext\n"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestWriteReport_GitLab(t *testing.T) {
+	gitlab := func(findings ...Finding) []gitlabIssue {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := WriteReport(&buf, FormatGitLab, Report{Findings: findings}); err != nil {
+			t.Fatal(err)
+		}
+		var issues []gitlabIssue
+		if err := json.Unmarshal(buf.Bytes(), &issues); err != nil {
+			t.Fatalf("invalid JSON %s: %v", buf.Bytes(), err)
+		}
+		return issues
+	}
+
+	if issues := gitlab(); issues == nil || len(issues) != 0 {
+		t.Errorf("expected an empty array without findings, got %v", issues)
+	}
+
+	first := Finding{Path: "a.go", Line: 3, Rule: RuleTrailingspace, Message: "trailing whitespace", Text: "x := 1 "}
+	same := first
+	same.Line = 8
+	other := first
+	other.Text = "y := 2 "
+	issues := gitlab(first, same, other)
+	if len(issues) != 3 {
+		t.Fatalf("expected 3 issues, got %v", issues)
+	}
+	i := issues[0]
+	if i.CheckName != "whitespace.trailingspace" || i.Severity != "minor" || i.Location.Path != "a.go" || i.Location.Lines.Begin != 3 || i.Description != "trailing whitespace" {
+		t.Errorf("unexpected issue %+v", i)
+	}
+	fingerprints := map[string]bool{}
+	for _, i := range issues {
+		fingerprints[i.Fingerprint] = true
+	}
+	if len(fingerprints) != 3 {
+		t.Errorf("expected distinct fingerprints, got %v", issues)
+	}
+
+	// A problem keeps its fingerprint when its line moves
+	moved := first
+	moved.Line = 20
+	if got := gitlab(moved)[0].Fingerprint; got != issues[0].Fingerprint {
+		t.Errorf("fingerprint changed when the line moved: %s != %s", got, issues[0].Fingerprint)
+	}
+}
@@ -0,0 +1,53 @@
+//go:build unix
+
+package whitespace
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestSpecialFiles_Skipped(t *testing.T) {
+	dir := t.TempDir()
+	text := filepath.Join(dir, "text.txt")
+	if err := os.WriteFile(text, []byte("text\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// FIFO: opening it for detection would block the walk forever
+	fifo := filepath.Join(dir, "fifo")
+	if err := syscall.Mkfifo(fifo, 0o644); err != nil {
+		t.Skipf("mkfifo not supported: %v", err)
+	}
+
+	// Socket
+	sock := filepath.Join(dir, "sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets not supported: %v", err)
+	}
+	defer l.Close()
+
+	// Device, reached through a followed symlink
+	if err := os.Symlink(os.DevNull, filepath.Join(dir, "null")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	mock := &mockProcessor{}
+	if err := processTarget(dir, Options{Symlinks: SymlinksFollow}, mock.process); err != nil {
+		t.Fatal(err)
+	}
+	if len(mock.processedFiles) != 1 || mock.processedFiles[0] != text {
+		t.Errorf("expected only %s to be processed, got %v", text, mock.processedFiles)
+	}
+
+	// Naming a special file explicitly is an error rather than a hang
+	for _, path := range []string{fifo, sock} {
+		if err := processTarget(path, Options{}, mock.process); err == nil {
+			t.Errorf("expected error for special file %s", filepath.Base(path))
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package whitespace
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// Suppression directives
+//
+// Directives are recognized anywhere on a line, so they can be written in
+// whatever comment syntax the file uses. Each takes an optional list of rule
+// names separated by spaces or commas; without one it applies to all rules.
+// Text after the rules, such as a reason or a closing "-->", is ignored.
+//
+//   - "whitespace:ignore-file" within the first ignoreFileLines lines turns
+//     the rules off for the whole file.
+//   - "whitespace:disable-next-line" turns them off for the following line.
+//   - "whitespace:disable" turns them off from its own line until a matching
+//     "whitespace:enable" (or the end of the file); "whitespace:enable"
+//     without rules ends every disabled rule.
+//
+// For example, in Markdown:
+//
+//	<!-- whitespace:disable-next-line trailingspace -->
+//	A line ending in two spaces for a hard line break
+//
+// The newline rule is suppressed when the last line of the file is.
+
+// ignoreFileLines is how far into a file whitespace:ignore-file is honored
+const ignoreFileLines = 10
+
+// directive matches a directive and its optional rule list
+var directive = regexp.MustCompile(`whitespace:(ignore-file|disable-next-line|disable|enable)\b((?:[ \t,]+(?:trailingspace|newline)\b)*)`)
+
+// suppressions records which rules directives turn off on each line
+type suppressions struct {
+	lines []map[string]bool // rules turned off, indexed by 0-based line
+}
+
+// parseSuppressions reads the directives in text. It returns nil, which
+// suppresses nothing, for the common case of a file without directives.
+func parseSuppressions(text []byte) *suppressions {
+	if !bytes.Contains(text, []byte("whitespace:")) {
+		return nil
+	}
+	lines := strings.Split(string(text), "\n")
+	s := &suppressions{lines: make([]map[string]bool, len(lines))}
+	file := map[string]bool{}
+	active := map[string]bool{}
+	next := map[string]bool{}
+
+	for i, line := range lines {
+		current := next
+		next = map[string]bool{}
+		for _, m := range directive.FindAllStringSubmatch(line, -1) {
+			names := directiveRules(m[2])
+			switch m[1] {
+			case "ignore-file":
+				if i < ignoreFileLines {
+					addRules(file, names)
+				}
+			case "disable-next-line":
+				addRules(next, names)
+			case "disable":
+				addRules(active, names)
+			case "enable":
+				for _, r := range names {
+					delete(active, r)
+				}
+			}
+		}
+		for r := range active {
+			current[r] = true
+		}
+		s.lines[i] = current
+	}
+
+	for _, set := range s.lines {
+		for r := range file {
+			set[r] = true
+		}
+	}
+	return s
+}
+
+// suppressed reports whether rule is turned off on the 1-based line
+func (s *suppressions) suppressed(line int, rule string) bool {
+	if s == nil || line < 1 || line > len(s.lines) {
+		return false
+	}
+	return s.lines[line-1][rule]
+}
+
+// filter drops the findings on suppressed lines
+func (s *suppressions) filter(findings []Finding) []Finding {
+	if s == nil {
+		return findings
+	}
+	out := findings[:0]
+	for _, f := range findings {
+		if !s.suppressed(f.Line, f.Rule) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// directiveRules returns the rules named in a directive's argument, or all rules
+func directiveRules(arg string) []string {
+	names := strings.FieldsFunc(arg, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ','
+	})
+	if len(names) == 0 {
+		return rules
+	}
+	return names
+}
+
+func addRules(set map[string]bool, names []string) {
+	for _, r := range names {
+		set[r] = true
+	}
+}
@@ -0,0 +1,117 @@
+package whitespace
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSuppressions_Trailingspace(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		lines    []int // lines still reported by the check
+	}{
+		{
+			name:     "disable-next-line",
+			input:    "<!-- whitespace:disable-next-line trailingspace -->\nhard break  \nother  \n",
+			expected: "<!-- whitespace:disable-next-line trailingspace -->\nhard break  \nother\n",
+			lines:    []int{3},
+		},
+		{
+			name:     "disable-next-line without rules",
+			input:    "# whitespace:disable-next-line\nkeep \n",
+			expected: "# whitespace:disable-next-line\nkeep \n",
+		},
+		{
+			name:     "disable-next-line for another rule",
+			input:    "// whitespace:disable-next-line newline\nfix \n",
+			expected: "// whitespace:disable-next-line newline\nfix\n",
+			lines:    []int{2},
+		},
+		{
+			name:     "disable and enable block",
+			input:    "a \n# whitespace:disable trailingspace\nb \nc \n# whitespace:enable trailingspace\nd \n",
+			expected: "a\n# whitespace:disable trailingspace\nb \nc \n# whitespace:enable trailingspace\nd\n",
+			lines:    []int{1, 6},
+		},
+		{
+			name:     "enable without rules ends all",
+			input:    "/* whitespace:disable */\nb \n/* whitespace:enable */\nc \n",
+			expected: "/* whitespace:disable */\nb \n/* whitespace:enable */\nc\n",
+			lines:    []int{4},
+		},
+		{
+			name:     "ignore-file",
+			input:    "#!/bin/sh\n# whitespace:ignore-file\necho  \n",
+			expected: "#!/bin/sh\n# whitespace:ignore-file\necho  \n",
+		},
+		{
+			name:     "ignore-file too late",
+			input:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n# whitespace:ignore-file\nx \n",
+			expected: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n# whitespace:ignore-file\nx\n",
+			lines:    []int{12},
+		},
+		{
+			name:     "trailing explanation",
+			input:    "# whitespace:disable-next-line trailingspace (heredoc)\nx \ny \n",
+			expected: "# whitespace:disable-next-line trailingspace (heredoc)\nx \ny\n",
+			lines:    []int{3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(fixTrailingspace([]byte(tt.input))); got != tt.expected {
+				t.Errorf("fix: expected %q, got %q", tt.expected, got)
+			}
+			findings := checkTrailingspace([]byte(tt.input))
+			if len(findings) != len(tt.lines) {
+				t.Fatalf("check: expected lines %v, got %v", tt.lines, findings)
+			}
+			for i, f := range findings {
+				if f.Line != tt.lines[i] {
+					t.Errorf("check: expected line %d, got %d", tt.lines[i], f.Line)
+				}
+			}
+		})
+	}
+}
+
+func TestSuppressions_Newline(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		suppressed bool
+	}{
+		{"no directive", "a", false},
+		{"ignore-file newline", "// whitespace:ignore-file newline\na", true},
+		{"ignore-file trailingspace only", "// whitespace:ignore-file trailingspace\na", false},
+		{"disabled through end of file", "a\n# whitespace:disable newline\nb\n\n\n", true},
+		{"disable-next-line on last line", "# whitespace:disable-next-line newline\nb", true},
+		{"disable-next-line earlier", "# whitespace:disable-next-line newline\nb\nc", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unchanged := string(fixNewline([]byte(tt.input))) == tt.input
+			if unchanged != tt.suppressed {
+				t.Errorf("fix: expected unchanged = %v", tt.suppressed)
+			}
+			if reported := len(checkNewline([]byte(tt.input))) > 0; reported == tt.suppressed {
+				t.Errorf("check: expected reported = %v", !tt.suppressed)
+			}
+		})
+	}
+}
+
+func TestSuppressions_ProcessFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"fixture.txt": "# whitespace:ignore-file\nexact output   \n",
+	})
+	if err := ProcessTrailingspaceWithOptions(dir, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTrailingspaceFileContent(t, filepath.Join(dir, "fixture.txt")); got != "# whitespace:ignore-file\nexact output   \n" {
+		t.Errorf("expected ignored file to be unchanged, got %q", got)
+	}
+}
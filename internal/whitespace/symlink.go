@@ -0,0 +1,76 @@
+package whitespace
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// SymlinkPolicy controls how symbolic links are treated during walks and for single-file targets
+type SymlinkPolicy string
+
+const (
+	// SymlinksSkip ignores symbolic links entirely (default)
+	SymlinksSkip SymlinkPolicy = "skip"
+	// SymlinksFollow processes link targets wherever they point
+	SymlinksFollow SymlinkPolicy = "follow"
+	// SymlinksFollowWithinRoot processes link targets only if they resolve inside the target root
+	SymlinksFollowWithinRoot SymlinkPolicy = "follow-within-root"
+)
+
+// ParseSymlinkPolicy validates a --symlinks value; the empty string selects SymlinksSkip
+func ParseSymlinkPolicy(s string) (SymlinkPolicy, error) {
+	switch p := SymlinkPolicy(s); p {
+	case "":
+		return SymlinksSkip, nil
+	case SymlinksSkip, SymlinksFollow, SymlinksFollowWithinRoot:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid symlink policy %q (want skip, follow or follow-within-root)", s)
+}
+
+// follows reports whether the policy follows links at all
+func (p SymlinkPolicy) follows() bool {
+	return p == SymlinksFollow || p == SymlinksFollowWithinRoot
+}
+
+// resolveSymlink resolves path to its final, absolute target. Relative link targets are
+// resolved against the directory containing the link, and chains are followed.
+// ok is false (with a nil error) for dangling links and link loops, which are
+// skipped rather than treated as failures.
+func resolveSymlink(path string) (real string, ok bool, err error) {
+	real, err = filepath.EvalSymlinks(path)
+	if err == nil {
+		real, err = filepath.Abs(real)
+		return real, err == nil, err
+	}
+	// EvalSymlinks does not expose a typed loop error, so ask the OS
+	if _, statErr := os.Stat(path); errors.Is(statErr, fs.ErrNotExist) || errors.Is(statErr, syscall.ELOOP) {
+		return "", false, nil
+	}
+	return "", false, err
+}
+
+// isWithin reports whether path is root or lies beneath it. Both must be
+// resolved (symlink-free) paths for the answer to be meaningful.
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// workdirRoot returns the resolved working directory, which confines
+// SymlinksFollowWithinRoot for explicitly named single files
+func workdirRoot() (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(wd)
+}
@@ -0,0 +1,219 @@
+package whitespace
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// createSymlinkStructure creates a root with links pointing inside it, outside it and back at itself:
+//
+//	root/file.txt
+//	root/sub/inner.txt
+//	root/link-file.txt -> file.txt (relative, resolved against root/)
+//	root/sub/up -> ..               (loop)
+//	root/link-sub -> sub            (second route to sub/, walked first but skipped)
+//	root/outside.txt -> ../outside/secret.txt
+//	root/link-outside -> ../outside
+//	root/dangling -> missing.txt
+//	outside/secret.txt
+func createSymlinkStructure(t *testing.T) (root, outside string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	root = filepath.Join(tmpDir, "root")
+	outside = filepath.Join(tmpDir, "outside")
+
+	for _, d := range []string{root, filepath.Join(root, "sub"), outside} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(root, "file.txt"):         "file\n",
+		filepath.Join(root, "sub", "inner.txt"): "inner\n",
+		filepath.Join(outside, "secret.txt"):    "secret\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		filepath.Join(root, "link-file.txt"): "file.txt",
+		filepath.Join(root, "sub", "up"):     "..",
+		filepath.Join(root, "link-sub"):      "sub",
+		filepath.Join(root, "outside.txt"):   filepath.Join("..", "outside", "secret.txt"),
+		filepath.Join(root, "link-outside"):  filepath.Join("..", "outside"),
+		filepath.Join(root, "dangling"):      "missing.txt",
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+	return root, outside
+}
+
+func relPaths(t *testing.T, root string, paths []string) []string {
+	t.Helper()
+	rel := make([]string, 0, len(paths))
+	for _, p := range paths {
+		r, err := filepath.Rel(root, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rel = append(rel, filepath.ToSlash(r))
+	}
+	sort.Strings(rel)
+	return rel
+}
+
+func TestSymlinkPolicy_Walk(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   SymlinkPolicy
+		expected []string
+	}{
+		{
+			name:     "default skips links",
+			policy:   "",
+			expected: []string{"file.txt", "sub/inner.txt"},
+		},
+		{
+			name:   "follow",
+			policy: SymlinksFollow,
+			expected: []string{
+				"file.txt",
+				"link-file.txt",
+				"link-outside/secret.txt",
+				"outside.txt",
+				"sub/inner.txt",
+			},
+		},
+		{
+			name:   "follow within root",
+			policy: SymlinksFollowWithinRoot,
+			expected: []string{
+				"file.txt",
+				"link-file.txt",
+				"sub/inner.txt",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, _ := createSymlinkStructure(t)
+			mock := &mockProcessor{}
+			if err := processTarget(root, Options{Symlinks: tt.policy}, mock.process); err != nil {
+				t.Fatal(err)
+			}
+
+			got := relPaths(t, root, mock.processedFiles)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("expected %v, got %v", tt.expected, got)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestSymlinkPolicy_LinkToPrunedDir(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{".hidden/a.txt": "a \n", "excluded/b.txt": "b \n"})
+	for name, target := range map[string]string{"link-hidden": ".hidden", "link-excluded": "excluded"} {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+
+	// A link is the only route to a directory the walk prunes, so it is followed
+	for _, policy := range []SymlinkPolicy{SymlinksFollow, SymlinksFollowWithinRoot} {
+		mock := &mockProcessor{}
+		opts := Options{Symlinks: policy, ExcludePatterns: []string{"/excluded/"}}
+		if err := processTarget(root, opts, mock.process); err != nil {
+			t.Fatal(err)
+		}
+		got := relPaths(t, root, mock.processedFiles)
+		if len(got) != 2 || got[0] != "link-excluded/b.txt" || got[1] != "link-hidden/a.txt" {
+			t.Errorf("%s: expected the files through both links, got %v", policy, got)
+		}
+	}
+}
+
+func TestSymlinkPolicy_SingleTarget(t *testing.T) {
+	root, _ := createSymlinkStructure(t)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	tests := []struct {
+		name      string
+		target    string
+		policy    SymlinkPolicy
+		processed bool
+	}{
+		{"skip link inside root", "link-file.txt", SymlinksSkip, false},
+		{"follow link inside root", "link-file.txt", SymlinksFollow, true},
+		{"follow link outside root", "outside.txt", SymlinksFollow, true},
+		{"within root allows inside", "link-file.txt", SymlinksFollowWithinRoot, true},
+		{"within root refuses outside", "outside.txt", SymlinksFollowWithinRoot, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockProcessor{}
+			if err := processTarget(tt.target, Options{Symlinks: tt.policy}, mock.process); err != nil {
+				t.Fatal(err)
+			}
+			if processed := len(mock.processedFiles) == 1; processed != tt.processed {
+				t.Errorf("expected processed=%v, got %v", tt.processed, mock.processedFiles)
+			}
+		})
+	}
+
+	// Dangling links are an error when named explicitly
+	if err := processTarget("dangling", Options{Symlinks: SymlinksFollow}, (&mockProcessor{}).process); err == nil {
+		t.Error("expected error for dangling symlink target")
+	}
+}
+
+func TestLooksText_RelativeSymlink(t *testing.T) {
+	root, _ := createSymlinkStructure(t)
+
+	// The link target is relative to root/, not to the working directory
+	isText, err := LooksText(filepath.Join(root, "link-file.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isText {
+		t.Error("expected relative symlink to a text file to look like text")
+	}
+
+	isText, err = LooksText(filepath.Join(root, "dangling"))
+	if err != nil || isText {
+		t.Errorf("expected dangling symlink to be non-text without error, got %v, %v", isText, err)
+	}
+}
+
+func TestParseSymlinkPolicy(t *testing.T) {
+	for _, s := range []string{"", "skip", "follow", "follow-within-root"} {
+		if _, err := ParseSymlinkPolicy(s); err != nil {
+			t.Errorf("ParseSymlinkPolicy(%q) failed: %v", s, err)
+		}
+	}
+	if _, err := ParseSymlinkPolicy("always"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
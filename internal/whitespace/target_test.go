@@ -0,0 +1,60 @@
+package whitespace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSingleFileTarget_Selection(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"docs/guide.md":       "guide\n",
+		"docs/old/legacy.md":  "legacy\n",
+		"src/main.go":         "package main\n",
+		"vendor/lib/lib.go":   "package lib\n",
+		".github/ci.yml":      "on: push\n",
+		"assets/image.png":    "\x89PNG\r\n\x1a\n\x00\x00",
+		"assets/readable.txt": "readable\n",
+	})
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	tests := []struct {
+		name      string
+		target    string
+		opts      Options
+		processed bool
+	}{
+		{"plain file", "src/main.go", Options{}, true},
+		{"binary is skipped", "assets/image.png", Options{}, false},
+		{"binary with force", "assets/image.png", Options{Force: true}, true},
+		{"excluded by name", "src/main.go", Options{ExcludePatterns: []string{"*.go"}}, false},
+		{"excluded by anchored pattern", "docs/guide.md", Options{ExcludePatterns: []string{"docs/*.md"}}, false},
+		{"excluded parent directory", "vendor/lib/lib.go", Options{ExcludePatterns: []string{"vendor/"}}, false},
+		{"excluded with ./ prefix", "./vendor/lib/lib.go", Options{ExcludePatterns: []string{"/vendor/"}}, false},
+		{"excluded by absolute path", filepath.Join(tmpDir, "docs", "old", "legacy.md"), Options{ExcludePatterns: []string{"docs/old/"}}, false},
+		{"force does not override excludes", "assets/image.png", Options{Force: true, ExcludePatterns: []string{"assets/"}}, false},
+		{"not included by type", "docs/guide.md", Options{Types: []string{"go"}}, false},
+		{"included by type", "src/main.go", Options{Types: []string{"go"}}, true},
+		{"hidden directory named explicitly", ".github/ci.yml", Options{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockProcessor{}
+			if err := processTarget(tt.target, tt.opts, mock.process); err != nil {
+				t.Fatal(err)
+			}
+			if processed := len(mock.processedFiles) == 1; processed != tt.processed {
+				t.Errorf("expected processed=%v, got %v", tt.processed, mock.processedFiles)
+			}
+		})
+	}
+}
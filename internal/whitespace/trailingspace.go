@@ -1,36 +1,112 @@
 package whitespace
 
 import (
-	"os"
+	"context"
 	"regexp"
 	"strings"
 )
 
-// removeTrailingWhitespace removes trailing spaces and tabs from each line in a file
-func removeTrailingWhitespace(path string) error {
-	input, err := os.ReadFile(path)
-	if err != nil {
-		return err
+// trailingWhitespace matches spaces and tabs at the end of a line, before any CR of a CRLF ending
+var trailingWhitespace = regexp.MustCompile(`[ \t]+(\r?)$`)
+
+// fixTrailingspace returns input with trailing spaces and tabs removed from each line
+func fixTrailingspace(input []byte) []byte {
+	lines, _ := trimLines(input, nil, nil)
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// checkTrailingspace reports each line that fixTrailingspace would change
+func checkTrailingspace(input []byte) []Finding {
+	return trailingspaceFindings(input, nil, nil)
+}
+
+// trailingspaceFixer returns the fix for the file at path, following
+// Markdown rules where they apply and leaving multi-line string literals of
+// known languages alone
+func trailingspaceFixer(path string, opts *Options) fixFunc {
+	md, lex := markdownFor(path, opts), literalLexerFor(path)
+	return func(input []byte) []byte {
+		lines, _ := trimLines(input, md, lex)
+		return []byte(strings.Join(lines, "\n"))
 	}
+}
+
+// trailingspaceChecker returns the check matching trailingspaceFixer
+func trailingspaceChecker(path string, opts *Options) checkFunc {
+	md, lex := markdownFor(path, opts), literalLexerFor(path)
+	return func(input []byte) []Finding {
+		return trailingspaceFindings(input, md, lex)
+	}
+}
+
+// markdownFor returns fresh Markdown line state for path, or nil for plain text
+func markdownFor(path string, opts *Options) *markdownLines {
+	if !isMarkdown(path, opts) {
+		return nil
+	}
+	return &markdownLines{hardBreaks: opts.HardBreaks, codeBlocks: opts.CodeBlocks}
+}
 
-	// Use regexp to remove trailing spaces and tabs from each line
-	trailingWhitespace := regexp.MustCompile(`[ \t]+$`)
+// trimLines splits input into lines and fixes each one not suppressed by a
+// directive or ending inside a literal found by lex, using Markdown rules if
+// md is set. It also returns the indexes of the lines that changed.
+func trimLines(input []byte, md *markdownLines, lex literalLexer) ([]string, []int) {
 	lines := strings.Split(string(input), "\n")
+	sup := parseSuppressions(input)
+	var changed []int
+	if md != nil {
+		md.fence = ""
+	}
+	var literal []bool
+	if lex != nil {
+		literal = lex(string(input))
+	}
 
-	// Process each line except handle the last one carefully to preserve EOF newlines
+	// The last element is what follows the final newline, so EOF newlines are preserved
 	for i, line := range lines {
-		lines[i] = trailingWhitespace.ReplaceAllString(line, "")
+		fixed := trailingWhitespace.ReplaceAllString(line, "$1")
+		if md != nil {
+			body, cr := strings.CutSuffix(line, "\r")
+			next := ""
+			if i+1 < len(lines) {
+				next = lines[i+1]
+			}
+			fixed = md.fix(body, next)
+			if cr {
+				fixed += "\r"
+			}
+		}
+		if fixed == line || sup.suppressed(i+1, RuleTrailingspace) || (literal != nil && literal[i]) {
+			continue
+		}
+		lines[i] = fixed
+		changed = append(changed, i)
 	}
+	return lines, changed
+}
 
-	output := strings.Join(lines, "\n")
-
-	// Write back to file
-	info, err := os.Stat(path)
-	if err != nil {
-		return err
+// trailingspaceFindings reports the lines trimLines would change
+func trailingspaceFindings(input []byte, md *markdownLines, lex literalLexer) []Finding {
+	original := strings.Split(string(input), "\n")
+	_, changed := trimLines(input, md, lex)
+	findings := make([]Finding, 0, len(changed))
+	for _, i := range changed {
+		text := strings.TrimSuffix(original[i], "\r")
+		findings = append(findings, Finding{
+			Line:    i + 1,
+			Column:  endColumn(strings.TrimRight(text, " \t")),
+			Rule:    RuleTrailingspace,
+			Message: "trailing whitespace",
+			Text:    text,
+		})
 	}
+	return findings
+}
 
-	return os.WriteFile(path, []byte(output), info.Mode())
+// removeTrailingWhitespace removes trailing spaces and tabs from each line in a file
+func removeTrailingWhitespace(path string) error {
+	_, err := rewriteFile(path, fixTrailingspace, &Options{})
+	return err
 }
 
 // ProcessTrailingspace processes a file or directory to remove trailing whitespace with default options.
@@ -40,5 +116,26 @@ func ProcessTrailingspace(target string) error {
 
 // ProcessTrailingspaceWithOptions processes a file or directory to remove trailing whitespace with the given options.
 func ProcessTrailingspaceWithOptions(target string, opts Options) error {
-	return processTarget(target, opts, removeTrailingWhitespace)
+	return processTarget(target, opts, func(path string) error {
+		_, err := rewriteFile(path, trailingspaceFixer(path, &opts), &opts)
+		return err
+	})
+}
+
+// CheckTrailingspaceWithOptions reports lines with trailing whitespace in
+// files under target, without modifying them.
+func CheckTrailingspaceWithOptions(target string, opts Options) ([]Finding, error) {
+	return checkTarget(target, opts, func(path string) checkFunc {
+		return trailingspaceChecker(path, &opts)
+	})
+}
+
+// WatchTrailingspaceWithOptions processes target like
+// ProcessTrailingspaceWithOptions, then keeps fixing files under it as they
+// are created or saved until ctx is done.
+func WatchTrailingspaceWithOptions(ctx context.Context, target string, opts Options) error {
+	return watchTarget(ctx, target, opts, func(path string) error {
+		_, err := rewriteFile(path, trailingspaceFixer(path, &opts), &opts)
+		return err
+	})
 }
@@ -126,6 +126,11 @@ func TestRemoveTrailingWhitespace(t *testing.T) {
 			input:    "no final newline  \t",
 			expected: "no final newline",
 		},
+		{
+			name:     "CRLF line endings keep their CR",
+			input:    "line1  \r\n\t\r\nline3\r\n",
+			expected: "line1\r\n\r\nline3\r\n",
+		},
 		{
 			name:     "multiple consecutive empty lines with whitespace",
 			input:    "content\n  \n\t\n \t \nmore content\n",
@@ -0,0 +1,268 @@
+package whitespace
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long a file must go without events before it is
+// processed, so an editor's rapid sequence of writes is handled once
+var watchDebounce = 150 * time.Millisecond
+
+// fileStamp identifies a version of a file's content
+type fileStamp struct {
+	size    int64
+	modTime int64
+}
+
+func stampOf(info fs.FileInfo) fileStamp {
+	return fileStamp{size: info.Size(), modTime: info.ModTime().UnixNano()}
+}
+
+// watchState carries the state of a single watchTarget run
+type watchState struct {
+	target   string // file or directory as given by the caller
+	rootReal string // resolved target, set when following symlinks
+	isDir    bool
+	opts     *Options
+	process  ProcessFileFunc
+	events   *fsnotify.Watcher
+	dirs     map[string]bool      // directories being watched
+	pending  map[string]bool      // files changed since the last flush
+	seen     map[string]fileStamp // files as last processed, so our own writes are ignored
+}
+
+// watchTarget processes target, then keeps processing files under it as
+// they are created or written until ctx is done. Directories are selected
+// like a walk does: hidden and excluded directories are not watched, and
+// directories created or removed while watching are added and dropped. The
+// initial run fails like processTarget; later per-file errors are reported
+// as warnings so the watch goes on.
+func watchTarget(ctx context.Context, target string, opts Options, processFile ProcessFileFunc) error {
+	if err := compileExcludePatterns(&opts); err != nil {
+		return err
+	}
+	if err := compileIncludes(&opts); err != nil {
+		return err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+
+	events, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer events.Close()
+	s := &watchState{
+		target:  filepath.Clean(target),
+		isDir:   info.IsDir(),
+		opts:    &opts,
+		events:  events,
+		dirs:    make(map[string]bool),
+		pending: make(map[string]bool),
+		seen:    make(map[string]fileStamp),
+	}
+	if s.isDir && opts.Symlinks.follows() {
+		if s.rootReal, err = filepath.EvalSymlinks(target); err != nil {
+			return err
+		}
+	}
+	s.process = func(path string) error {
+		err := processFile(path)
+		s.remember(path)
+		return err
+	}
+
+	// Watch before the initial run so no change made during it is missed
+	if s.isDir {
+		if err := s.watchTree(s.target, false); err != nil {
+			return err
+		}
+	} else if err := events.Add(filepath.Dir(s.target)); err != nil {
+		return err
+	}
+	if err := processTarget(target, opts, s.process); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(watchDebounce)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-events.Events:
+			if !ok {
+				return nil
+			}
+			if s.handle(ev) {
+				timer.Reset(watchDebounce)
+			}
+		case err, ok := <-events.Errors:
+			if !ok {
+				return nil
+			}
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				// Events were lost, so anything may have changed
+				warnf(s.opts, "%s: too many changes at once, processing everything again", target)
+				if err := processTarget(target, opts, s.process); err != nil {
+					warnf(s.opts, "%v", err)
+				}
+				continue
+			}
+			warnf(s.opts, "%s: %v", target, err)
+		case <-timer.C:
+			s.flush()
+		}
+	}
+}
+
+// handle records a file system event, reporting whether a file is now
+// pending
+func (s *watchState) handle(ev fsnotify.Event) bool {
+	path := filepath.Clean(ev.Name)
+	if !s.isDir {
+		if path != s.target || !ev.Has(fsnotify.Create) && !ev.Has(fsnotify.Write) {
+			return false
+		}
+		s.pending[path] = true
+		return true
+	}
+
+	switch {
+	case ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename):
+		// The file or directory is gone from this path
+		s.unwatchTree(path)
+		delete(s.pending, path)
+		delete(s.seen, path)
+		return false
+	case ev.Has(fsnotify.Create):
+		info, err := os.Lstat(path)
+		if err != nil {
+			return false
+		}
+		if info.IsDir() {
+			if s.skipDir(path) {
+				return false
+			}
+			// Files may have been written before the watch was added
+			if err := s.watchTree(path, true); err != nil {
+				warnf(s.opts, "%v", err)
+			}
+			return len(s.pending) > 0
+		}
+	case !ev.Has(fsnotify.Write):
+		return false
+	}
+	s.pending[path] = true
+	return true
+}
+
+// skipDir reports whether a directory under the target is not watched
+func (s *watchState) skipDir(path string) bool {
+	w := &walker{root: s.target, opts: s.opts}
+	return path != s.target && w.skipDir(path)
+}
+
+// watchTree watches dir and the directories under it that a walk would
+// visit. With queue, the files found are marked pending.
+func (s *watchState) watchTree(dir string, queue bool) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// The directory may be gone already
+			if path == dir {
+				return nil
+			}
+			return filepath.SkipDir
+		}
+		if !d.IsDir() {
+			if queue {
+				s.pending[path] = true
+			}
+			return nil
+		}
+		if s.skipDir(path) {
+			return filepath.SkipDir
+		}
+		if err := s.events.Add(path); err != nil {
+			return err
+		}
+		s.dirs[path] = true
+		return nil
+	})
+}
+
+// unwatchTree stops watching dir and the directories under it
+func (s *watchState) unwatchTree(dir string) {
+	prefix := dir + string(filepath.Separator)
+	for d := range s.dirs {
+		if d == dir || strings.HasPrefix(d, prefix) {
+			// Removed directories lose their watch on their own
+			_ = s.events.Remove(d)
+			delete(s.dirs, d)
+		}
+	}
+}
+
+// remember records the state of path after it was processed
+func (s *watchState) remember(path string) {
+	if info, err := os.Stat(path); err == nil {
+		s.seen[path] = stampOf(info)
+	}
+}
+
+// flush processes the pending files that changed since they were last
+// processed. Files that are gone, such as an editor's temporary files, are
+// skipped.
+func (s *watchState) flush() {
+	paths := make([]string, 0, len(s.pending))
+	for path := range s.pending {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	clear(s.pending)
+
+	w := &walker{
+		root:        s.target,
+		opts:        s.opts,
+		processFile: s.process,
+		errs:        &errorCollector{keepGoing: true},
+		rootReal:    s.rootReal,
+	}
+	for _, path := range paths {
+		info, err := os.Lstat(path)
+		if err != nil {
+			continue
+		}
+		if seen, ok := s.seen[path]; ok && seen == stampOf(info) {
+			// Our own write
+			continue
+		}
+		switch {
+		case !s.isDir:
+			err = processTarget(path, *s.opts, s.process)
+		case info.Mode()&fs.ModeSymlink != 0:
+			err = w.visitSymlink(path, path)
+		case info.Mode().IsRegular():
+			err = w.visitFile(path, path, relativePath(s.target, path))
+		}
+		if err != nil {
+			warnf(s.opts, "%s: %v", path, err)
+		}
+	}
+	if err := w.errs.err(); err != nil {
+		for _, e := range err.(*MultiError).Errors {
+			warnf(s.opts, "%v", e)
+		}
+	}
+}
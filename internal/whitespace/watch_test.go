@@ -0,0 +1,169 @@
+package whitespace
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startWatch runs watchTarget in the background with a short debounce,
+// counting the files processed. The watch is stopped when the test ends.
+func startWatch(t *testing.T, target string, opts Options) func(path string) int {
+	t.Helper()
+	saved := watchDebounce
+	watchDebounce = 20 * time.Millisecond
+	t.Cleanup(func() { watchDebounce = saved })
+
+	var mu sync.Mutex
+	counts := map[string]int{}
+	process := func(path string) error {
+		mu.Lock()
+		counts[path]++
+		mu.Unlock()
+		_, err := rewriteFile(path, fixTrailingspace, &opts)
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- watchTarget(ctx, target, opts, process)
+	}()
+	// Let the watches be added and the initial run finish
+	time.Sleep(100 * time.Millisecond)
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("watch failed: %v", err)
+		}
+	})
+	return func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return counts[path]
+	}
+}
+
+// eventually polls until path has the expected content
+func eventually(t *testing.T, path, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, _ := os.ReadFile(path)
+		if string(data) == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s: expected %q, got %q", path, want, data)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// unchanged waits a few debounce periods and checks path was not fixed
+func unchanged(t *testing.T, path, want string) {
+	t.Helper()
+	time.Sleep(10 * watchDebounce)
+	if got := readTrailingspaceFileContent(t, path); got != want {
+		t.Errorf("%s: expected it to be left alone, got %q", path, got)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"initial.txt":      "initial  \n",
+		"existing/old.txt": "old\n",
+	})
+	startWatch(t, dir, Options{ExcludePatterns: []string{"skip/"}})
+	eventually(t, filepath.Join(dir, "initial.txt"), "initial\n")
+
+	t.Run("saved file", func(t *testing.T) {
+		path := filepath.Join(dir, "existing", "old.txt")
+		writeFiles(t, dir, map[string]string{"existing/old.txt": "edited \n"})
+		eventually(t, path, "edited\n")
+	})
+
+	t.Run("new directory", func(t *testing.T) {
+		writeFiles(t, dir, map[string]string{"new/deeper/file.txt": "new\t\n"})
+		eventually(t, filepath.Join(dir, "new", "deeper", "file.txt"), "new\n")
+	})
+
+	t.Run("recreated directory", func(t *testing.T) {
+		if err := os.RemoveAll(filepath.Join(dir, "new")); err != nil {
+			t.Fatal(err)
+		}
+		writeFiles(t, dir, map[string]string{"new/again.txt": "again \n"})
+		eventually(t, filepath.Join(dir, "new", "again.txt"), "again\n")
+	})
+
+	t.Run("atomic save", func(t *testing.T) {
+		path := filepath.Join(dir, "atomic.txt")
+		writeFiles(t, dir, map[string]string{"atomic.txt.tmp": "atomic  \n"})
+		if err := os.Rename(path+".tmp", path); err != nil {
+			t.Fatal(err)
+		}
+		eventually(t, path, "atomic\n")
+	})
+
+	t.Run("hidden directory", func(t *testing.T) {
+		writeFiles(t, dir, map[string]string{".hidden/file.txt": "hidden  \n"})
+		unchanged(t, filepath.Join(dir, ".hidden", "file.txt"), "hidden  \n")
+	})
+
+	t.Run("excluded directory", func(t *testing.T) {
+		writeFiles(t, dir, map[string]string{"skip/file.txt": "skip  \n"})
+		unchanged(t, filepath.Join(dir, "skip", "file.txt"), "skip  \n")
+	})
+
+	t.Run("binary file", func(t *testing.T) {
+		writeFiles(t, dir, map[string]string{"data.bin": "\x00\x01  \n"})
+		unchanged(t, filepath.Join(dir, "data.bin"), "\x00\x01  \n")
+	})
+}
+
+func TestWatch_IgnoresOwnWrites(t *testing.T) {
+	dir := t.TempDir()
+	count := startWatch(t, dir, Options{})
+
+	path := filepath.Join(dir, "file.txt")
+	// Several quick writes, like an editor saving, are processed once
+	for _, content := range []string{"one  \n", "one two  \n", "one two three  \n"} {
+		writeFiles(t, dir, map[string]string{"file.txt": content})
+	}
+	eventually(t, path, "one two three\n")
+	// The fix's own write must not be processed again
+	time.Sleep(10 * watchDebounce)
+	if n := count(path); n != 1 {
+		t.Errorf("expected the file to be processed once, got %d", n)
+	}
+
+	writeFiles(t, dir, map[string]string{"file.txt": "saved again  \n"})
+	eventually(t, path, "saved again\n")
+	time.Sleep(10 * watchDebounce)
+	if n := count(path); n != 2 {
+		t.Errorf("expected the file to be processed twice, got %d", n)
+	}
+}
+
+func TestWatch_SingleFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"watched.txt": "watched  \n", "other.txt": "other\n"})
+	path := filepath.Join(dir, "watched.txt")
+	startWatch(t, path, Options{})
+	eventually(t, path, "watched\n")
+
+	writeFiles(t, dir, map[string]string{"watched.txt": "saved  \n", "other.txt": "other  \n"})
+	eventually(t, path, "saved\n")
+	unchanged(t, filepath.Join(dir, "other.txt"), "other  \n")
+}
+
+func TestWatch_MissingTarget(t *testing.T) {
+	err := WatchTrailingspaceWithOptions(context.Background(), filepath.Join(t.TempDir(), "missing"), Options{})
+	if err == nil {
+		t.Error("expected an error for a missing target")
+	}
+}